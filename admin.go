@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// adminTokenHeader carries the shared secret for admin endpoints.
+const adminTokenHeader = "X-Admin-Token"
+
+// requireAdmin rejects requests whose adminTokenHeader does not match token.
+// Admin endpoints act on every user's data, so a session is not enough. An
+// empty token disables them entirely.
+func requireAdmin(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			writeJSONError(w, http.StatusForbidden, "Admin endpoints are disabled; start the server with -admin-token")
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(adminTokenHeader)), []byte(token)) != 1 {
+			writeJSONError(w, http.StatusUnauthorized, "Invalid admin token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// backupHandler streams a consistent copy of the whole database as an
+// attachment. The copy is written to a temporary file with VACUUM INTO first,
+// so the database is only read-locked while the snapshot is taken, not for
+// the length of a slow download.
+func backupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "bible_app-backup-")
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to create backup")
+		slog.Error("Backup failed", "err", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "backup.db")
+	if _, err := db.ExecContext(r.Context(), `VACUUM INTO ?`, path); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to create backup")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to create backup")
+		slog.Error("Backup failed", "err", err)
+		return
+	}
+	defer f.Close()
+
+	now := time.Now().UTC()
+	name := fmt.Sprintf("bible_app-%s.db", now.Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	http.ServeContent(w, r, name, now, f)
+}
+
+// CleanupResult reports how many highlights /api/admin/cleanup removed for
+// each rule they broke.
+type CleanupResult struct {
+	Removed map[string]int `json:"removed"`
+	Total   int            `json:"total"`
+}
+
+// cleanupHandler deletes highlights that could no longer be created: those
+// whose owner no longer exists ("orphaned") and those failing one of
+// highlightRules, counted under the first rule they break. Highlights with a
+// userId of 0 were stored before accounts existed and are waiting for the
+// first user to register, so they are not orphaned. The tags and note
+// history of removed highlights go with them, and clients watching their
+// chapters are told of the deletions.
+func cleanupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	result := CleanupResult{Removed: map[string]int{"orphaned": 0}}
+	for _, rule := range highlightRules {
+		result.Removed[rule.name] = 0
+	}
+
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to begin transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	rows, err := tx.QueryContext(r.Context(), `SELECT `+highlightColumns+`, userId,
+	                                               userId != 0 AND userId NOT IN (SELECT id FROM users)
+	                                           FROM highlights`)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	var removed []Highlight
+	for rows.Next() {
+		var orphaned bool
+		var userID int64
+		h, err := scanHighlight(extraColumns{rows, []any{&userID, &orphaned}})
+		if err != nil {
+			rows.Close()
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		h.UserID = userID
+		if orphaned {
+			result.Removed["orphaned"]++
+			removed = append(removed, h)
+		} else if rule, err := brokenRule(h); err != nil {
+			result.Removed[rule]++
+			removed = append(removed, h)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	for _, h := range removed {
+		for _, query := range []string{
+			`DELETE FROM highlight_note_history WHERE highlightId = ?`,
+			`DELETE FROM highlight_tags WHERE highlightId = ?`,
+			`DELETE FROM highlights WHERE id = ?`,
+		} {
+			if _, err := tx.ExecContext(r.Context(), query, h.ID); err != nil {
+				writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+				slog.Error("Database error", "err", err)
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to commit transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	for _, h := range removed {
+		// Highlights already in the trash were announced as deleted then.
+		if h.DeletedAt == "" {
+			hub.publishDeleted(h)
+		}
+	}
+
+	result.Total = len(removed)
+	slog.Info("Removed invalid highlights", "total", result.Total)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCleanup(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	mux.HandleFunc("/api/admin/cleanup", requireAdmin("secret", cleanupHandler))
+	signUp(t, mux, "ann") // user 1; registering first claims nothing yet
+
+	now := timestamp()
+	store := func(h Highlight) {
+		t.Helper()
+		h.Type, h.Translation, h.Color, h.CreatedAt, h.UpdatedAt = "highlight-only", "KJV", "#ffff00", now, now
+		if _, err := insertHighlightStmt.Exec(insertHighlightArgs(h)...); err != nil {
+			t.Fatalf("storing %s: %v", h.ID, err)
+		}
+	}
+	store(Highlight{ID: "valid", VerseID: "verse-1-1-1", BookID: 1, Chapter: 1, End: 5, UserID: 1})
+	store(Highlight{ID: "legacy", VerseID: "verse-1-1-2", BookID: 1, Chapter: 1, End: 5, UserID: 0})
+	store(Highlight{ID: "orphan", VerseID: "verse-1-1-3", BookID: 1, Chapter: 1, End: 5, UserID: 99})
+	store(Highlight{ID: "no-book", VerseID: "verse-0-1-1", BookID: 0, Chapter: 1, End: 5, UserID: 1})
+	store(Highlight{ID: "backwards", VerseID: "verse-1-1-4", BookID: 1, Chapter: 1, Start: 9, End: 2, UserID: 1})
+	for _, stmt := range []string{
+		`INSERT INTO tags (id, userId, name) VALUES (1, 99, 'gone')`,
+		`INSERT INTO highlight_tags (highlightId, tagId) VALUES ('orphan', 1)`,
+		`INSERT INTO highlight_note_history (highlightId, note, replacedAt) VALUES ('orphan', 'old', '` + now + `'), ('backwards', 'old', '` + now + `')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	watcher := hub.register(chapterKey{1, "KJV", 1, 1})
+	defer hub.unregister(watcher)
+
+	if rec := serve(mux, http.MethodPost, "/api/admin/cleanup", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without the admin token: status %d, want 401", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/cleanup", nil)
+	req.Header.Set(adminTokenHeader, "secret")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var result CleanupResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 3 || result.Removed["orphaned"] != 1 || result.Removed["bookId"] != 1 || result.Removed["range"] != 1 {
+		t.Errorf("result = %+v, want one each of orphaned, bookId and range", result)
+	}
+
+	var left []string
+	rows, err := db.Query(`SELECT id FROM highlights ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		left = append(left, id)
+	}
+	if len(left) != 2 || left[0] != "legacy" || left[1] != "valid" {
+		t.Errorf("highlights left = %v, want [legacy valid]", left)
+	}
+
+	for _, table := range []string{"highlight_tags", "highlight_note_history"} {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("%s has %d rows left for removed highlights", table, n)
+		}
+	}
+
+	// Only "backwards" is in a chapter ann can be watching.
+	select {
+	case ev := <-watcher.send:
+		if ev.Type != "deleted" || ev.ID != "backwards" {
+			t.Errorf("event = %+v, want deleted backwards", ev)
+		}
+	default:
+		t.Error("no deleted event was published")
+	}
+}
+
+func TestBackupIsAUsableDatabase(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	mux.HandleFunc("/api/backup", requireAdmin("secret", backupHandler))
+	ann := signUp(t, mux, "ann")
+	if rec := serve(mux, http.MethodPost, "/api/highlights", testHighlight("h-1"), ann); rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", rec.Code, rec.Body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/backup", nil)
+	req.Header.Set(adminTokenHeader, "secret")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(got, `attachment; filename="bible_app-`) {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "backup.db")
+	if err := os.WriteFile(path, rec.Body.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+
+	var check string
+	if err := backup.QueryRow(`PRAGMA integrity_check`).Scan(&check); err != nil || check != "ok" {
+		t.Fatalf("integrity_check = %q, %v", check, err)
+	}
+	var id, username string
+	err = backup.QueryRow(`SELECT h.id, u.username FROM highlights h JOIN users u ON u.id = h.userId`).Scan(&id, &username)
+	if err != nil || id != "h-1" || username != "ann" {
+		t.Errorf("backup holds %q by %q, %v; want h-1 by ann", id, username, err)
+	}
+}
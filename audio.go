@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// audioSource builds links to chapter recordings hosted by an audio Bible
+// provider. Recordings are expected at
+// {baseURL}/{code}/{OSIS book}/{chapter}.mp3, where code is the provider's
+// name for the translation, such as https://audio.example.org/kjv/John/3.mp3.
+type audioSource struct {
+	baseURL string
+	codes   map[string]string // Translation to provider code
+}
+
+// audio is the configured provider, or nil when -audio-base-url is unset.
+var audio *audioSource
+
+// newAudioSource parses the -audio-translations list, in which each entry is
+// a translation optionally followed by =code, e.g. "KJV,ESV=esv2016". A bare
+// translation uses its lowercased name as the code.
+func newAudioSource(baseURL, translations string) (*audioSource, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("audio base URL %q must be an http or https URL", baseURL)
+	}
+
+	s := &audioSource{baseURL: strings.TrimSuffix(baseURL, "/"), codes: map[string]string{}}
+	for _, entry := range strings.Split(translations, ",") {
+		translation, code, hasCode := strings.Cut(strings.TrimSpace(entry), "=")
+		translation, code = strings.TrimSpace(translation), strings.TrimSpace(code)
+		if translation == "" {
+			continue
+		}
+		if !hasCode {
+			code = strings.ToLower(translation)
+		}
+		if code == "" {
+			return nil, fmt.Errorf("audio translation %q has an empty code", translation)
+		}
+		s.codes[translation] = code
+	}
+	if len(s.codes) == 0 {
+		return nil, fmt.Errorf("no audio translations listed")
+	}
+	return s, nil
+}
+
+// chapterURLs returns the links to the recording of a chapter, or nil if
+// the provider has none for the translation. Each chapter is currently a
+// single file.
+func (s *audioSource) chapterURLs(translation string, bookID, chapter int) []string {
+	code, ok := s.codes[translation]
+	if !ok {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s/%s/%s/%d.mp3", s.baseURL, url.PathEscape(code), osisBooks[bookID-1], chapter)}
+}
+
+// ChapterAudio is the body of /api/audio.
+type ChapterAudio struct {
+	Translation string   `json:"translation"`
+	BookID      int      `json:"bookId"`
+	Chapter     int      `json:"chapter"`
+	URLs        []string `json:"urls"`
+}
+
+// audioHandler returns where to stream a chapter's recording from, or 404
+// when no provider is configured or it has no audio for the translation.
+func audioHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	translation, bookID, chapter, err := parseChapterQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if bookID > len(metadata.Books) || chapter > metadata.Books[bookID-1].Chapters {
+		writeJSONError(w, http.StatusNotFound, "No such chapter")
+		return
+	}
+
+	var urls []string
+	if audio != nil {
+		urls = audio.chapterURLs(translation, bookID, chapter)
+	}
+	if len(urls) == 0 {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("No audio is available for %s", translation))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ChapterAudio{Translation: translation, BookID: bookID, Chapter: chapter, URLs: urls})
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// sessionCookieName is the cookie carrying the session token issued at login.
+const sessionCookieName = "session"
+
+// sessionLifetime is how long a login stays valid.
+const sessionLifetime = 30 * 24 * time.Hour
+
+// Credentials is the request body for registering and logging in.
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type userIDKey struct{}
+
+// userIDFromContext returns the authenticated user's ID stored by
+// requireUser. It is only meaningful inside handlers wrapped by requireUser.
+func userIDFromContext(ctx context.Context) int64 {
+	id, _ := ctx.Value(userIDKey{}).(int64)
+	return id
+}
+
+// requireUser rejects requests without a valid session cookie with 401, and
+// otherwise passes the user's ID to next through the request context.
+func requireUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(sessionCookieName)
+		if err != nil || cookie.Value == "" {
+			writeJSONError(w, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+
+		var userID int64
+		err = db.QueryRowContext(r.Context(), `SELECT userId FROM sessions WHERE token = ? AND expiresAt > ?`, cookie.Value, time.Now().Unix()).Scan(&userID)
+		if err == sql.ErrNoRows {
+			writeJSONError(w, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+			slog.Error("Database error", "err", err)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), userIDKey{}, userID)))
+	}
+}
+
+// maxPasswordBytes is the longest password bcrypt accepts; it refuses to hash
+// anything longer rather than silently truncating it.
+const maxPasswordBytes = 72
+
+// maxCredentialsBodyBytes bounds the body of register and login requests.
+const maxCredentialsBodyBytes = 16 << 10
+
+// decodeCredentials reads the username and password from the body of r,
+// trimming the username. It writes the error response itself and returns
+// false if the body is unreadable.
+func decodeCredentials(w http.ResponseWriter, r *http.Request) (Credentials, bool) {
+	var c Credentials
+	r.Body = http.MaxBytesReader(w, r.Body, maxCredentialsBodyBytes)
+	err := json.NewDecoder(r.Body).Decode(&c)
+	if isBodyTooLarge(err) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, bodyTooLargeMessage(maxCredentialsBodyBytes))
+		return c, false
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return c, false
+	}
+	c.Username = strings.TrimSpace(c.Username)
+	return c, true
+}
+
+// registerHandler creates an account and logs it in. The very first account
+// created adopts any highlights saved before accounts existed.
+func registerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	c, ok := decodeCredentials(w, r)
+	if !ok {
+		return
+	}
+	if c.Username == "" || c.Password == "" {
+		writeJSONError(w, http.StatusBadRequest, "Username and password are required")
+		return
+	}
+	if len(c.Password) > maxPasswordBytes {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Password must be at most %d bytes", maxPasswordBytes))
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(c.Password), bcrypt.DefaultCost)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to hash password")
+		slog.Error("Password hashing failed", "err", err)
+		return
+	}
+
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to begin transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	var existingUsers int
+	if err := tx.QueryRowContext(r.Context(), `SELECT COUNT(*) FROM users`).Scan(&existingUsers); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	result, err := tx.ExecContext(r.Context(), `INSERT INTO users (username, passwordHash) VALUES (?, ?)`, c.Username, string(hash))
+	if isUniqueViolation(err) {
+		writeJSONError(w, http.StatusConflict, "Username is already taken")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	userID, _ := result.LastInsertId()
+
+	if existingUsers == 0 {
+		if _, err := tx.ExecContext(r.Context(), `UPDATE highlights SET userId = ? WHERE userId = 0`, userID); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+			slog.Error("Database error", "err", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to commit transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	if err := startSession(r.Context(), w, userID); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to create session")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"username": c.Username})
+}
+
+// loginHandler checks a username and password and issues a session cookie.
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	c, ok := decodeCredentials(w, r)
+	if !ok {
+		return
+	}
+
+	var userID int64
+	var hash string
+	err := db.QueryRowContext(r.Context(), `SELECT id, passwordHash FROM users WHERE username = ?`, c.Username).Scan(&userID, &hash)
+	if err != nil && err != sql.ErrNoRows {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if err == sql.ErrNoRows || bcrypt.CompareHashAndPassword([]byte(hash), []byte(c.Password)) != nil {
+		writeJSONError(w, http.StatusUnauthorized, "Invalid username or password")
+		return
+	}
+
+	if err := startSession(r.Context(), w, userID); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to create session")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"username": c.Username})
+}
+
+// currentUserHandler returns the username of the logged-in user, letting the
+// frontend find out whether it needs to show the login form.
+func currentUserHandler(w http.ResponseWriter, r *http.Request) {
+	var username string
+	if err := db.QueryRowContext(r.Context(), `SELECT username FROM users WHERE id = ?`, userIDFromContext(r.Context())).Scan(&username); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"username": username})
+}
+
+// logoutHandler ends the current session, if any.
+func logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if _, err := db.ExecContext(r.Context(), `DELETE FROM sessions WHERE token = ?`, cookie.Value); err != nil {
+			slog.Error("Database error", "err", err)
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: sessionCookieName, Value: "", Path: "/", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// startSession stores a new random session token for userID and sets it as
+// the session cookie.
+func startSession(ctx context.Context, w http.ResponseWriter, userID int64) error {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(buf)
+	expires := time.Now().Add(sessionLifetime)
+
+	if _, err := db.ExecContext(ctx, `INSERT INTO sessions (token, userId, expiresAt) VALUES (?, ?, ?)`, token, userID, expires.Unix()); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// isUniqueViolation reports whether err is SQLite rejecting a duplicate value
+// in a UNIQUE column.
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Bookmark marks a whole chapter for quick access, unlike a highlight which
+// covers a range within a verse.
+type Bookmark struct {
+	ID          int64  `json:"id"`
+	Translation string `json:"translation"`
+	BookID      int    `json:"bookId"`
+	Chapter     int    `json:"chapter"`
+	Label       string `json:"label"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+const bookmarkColumns = `id, translation, bookId, chapter, label, createdAt`
+
+func scanBookmark(s rowScanner) (Bookmark, error) {
+	var b Bookmark
+	err := s.Scan(&b.ID, &b.Translation, &b.BookID, &b.Chapter, &b.Label, &b.CreatedAt)
+	return b, err
+}
+
+func bookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listBookmarksHandler(w, r)
+	case http.MethodPost:
+		createBookmarkHandler(w, r)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// bookmarkHandler routes requests for a single bookmark addressed as
+// /api/bookmarks/{id}.
+func bookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/bookmarks/"), 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid bookmark ID")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		getBookmarkHandler(w, r, id)
+	case http.MethodPut:
+		updateBookmarkHandler(w, r, id)
+	case http.MethodDelete:
+		deleteBookmarkHandler(w, r, id)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// listBookmarksHandler returns the user's bookmarks, most recent first.
+func listBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.QueryContext(r.Context(), `SELECT `+bookmarkColumns+` FROM bookmarks WHERE userId = ? ORDER BY createdAt DESC, id DESC`,
+		userIDFromContext(r.Context()))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	bookmarks := []Bookmark{}
+	for rows.Next() {
+		b, err := scanBookmark(rows)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		bookmarks = append(bookmarks, b)
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bookmarks)
+}
+
+// createBookmarkHandler stores a new bookmark. The ID and createdAt are
+// assigned by the server.
+func createBookmarkHandler(w http.ResponseWriter, r *http.Request) {
+	var b Bookmark
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if b.Translation == "" || b.BookID <= 0 || b.Chapter <= 0 {
+		writeJSONError(w, http.StatusBadRequest, "translation, bookId and chapter are required")
+		return
+	}
+	b.Label = strings.TrimSpace(b.Label)
+	b.CreatedAt = timestamp()
+
+	result, err := db.ExecContext(r.Context(), `INSERT INTO bookmarks (userId, translation, bookId, chapter, label, createdAt) VALUES (?, ?, ?, ?, ?, ?)`,
+		userIDFromContext(r.Context()), b.Translation, b.BookID, b.Chapter, b.Label, b.CreatedAt)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	b.ID, _ = result.LastInsertId()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(b)
+}
+
+func getBookmarkHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	b, err := scanBookmark(db.QueryRowContext(r.Context(), `SELECT `+bookmarkColumns+` FROM bookmarks WHERE id = ? AND userId = ?`,
+		id, userIDFromContext(r.Context())))
+	if err == sql.ErrNoRows {
+		writeJSONError(w, http.StatusNotFound, "Bookmark not found")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(b)
+}
+
+// updateBookmarkHandler renames a bookmark. Only the label can change; to
+// bookmark a different chapter, create a new bookmark.
+func updateBookmarkHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	var u struct {
+		Label string `json:"label"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := db.ExecContext(r.Context(), `UPDATE bookmarks SET label = ? WHERE id = ? AND userId = ?`,
+		strings.TrimSpace(u.Label), id, userIDFromContext(r.Context()))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		writeJSONError(w, http.StatusNotFound, "Bookmark not found")
+		return
+	}
+
+	getBookmarkHandler(w, r, id)
+}
+
+func deleteBookmarkHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	result, err := db.ExecContext(r.Context(), `DELETE FROM bookmarks WHERE id = ? AND userId = ?`, id, userIDFromContext(r.Context()))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		writeJSONError(w, http.StatusNotFound, "Bookmark not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
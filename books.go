@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// osisBooks holds the OSIS abbreviation of each book of the Protestant
+// canon, indexed by bookId-1 in the same order bolls.life numbers them.
+var osisBooks = [66]string{
+	"Gen", "Exod", "Lev", "Num", "Deut", "Josh", "Judg", "Ruth", "1Sam", "2Sam",
+	"1Kgs", "2Kgs", "1Chr", "2Chr", "Ezra", "Neh", "Esth", "Job", "Ps", "Prov",
+	"Eccl", "Song", "Isa", "Jer", "Lam", "Ezek", "Dan", "Hos", "Joel", "Amos",
+	"Obad", "Jonah", "Mic", "Nah", "Hab", "Zeph", "Hag", "Zech", "Mal",
+	"Matt", "Mark", "Luke", "John", "Acts", "Rom", "1Cor", "2Cor", "Gal", "Eph",
+	"Phil", "Col", "1Thess", "2Thess", "1Tim", "2Tim", "Titus", "Phlm", "Heb", "Jas",
+	"1Pet", "2Pet", "1John", "2John", "3John", "Jude", "Rev",
+}
+
+// osisBookIDs maps an OSIS book abbreviation back to its bookId.
+var osisBookIDs = func() map[string]int {
+	ids := make(map[string]int, len(osisBooks))
+	for i, abbrev := range osisBooks {
+		ids[abbrev] = i + 1
+	}
+	return ids
+}()
+
+// toOSIS returns the OSIS reference of a verse, such as "John.3.16", or ""
+// if bookID is not a book of the canon.
+func toOSIS(bookID, chapter, verse int) string {
+	if bookID < 1 || bookID > len(osisBooks) {
+		return ""
+	}
+	return fmt.Sprintf("%s.%d.%d", osisBooks[bookID-1], chapter, verse)
+}
+
+// bookNames holds the English name of each book, indexed by bookId-1, as
+// bolls.life spells them for English translations.
+var bookNames = [66]string{
+	"Genesis", "Exodus", "Leviticus", "Numbers", "Deuteronomy", "Joshua", "Judges", "Ruth",
+	"1 Samuel", "2 Samuel", "1 Kings", "2 Kings", "1 Chronicles", "2 Chronicles", "Ezra",
+	"Nehemiah", "Esther", "Job", "Psalms", "Proverbs", "Ecclesiastes", "Song of Solomon",
+	"Isaiah", "Jeremiah", "Lamentations", "Ezekiel", "Daniel", "Hosea", "Joel", "Amos",
+	"Obadiah", "Jonah", "Micah", "Nahum", "Habakkuk", "Zephaniah", "Haggai", "Zechariah",
+	"Malachi",
+	"Matthew", "Mark", "Luke", "John", "Acts", "Romans", "1 Corinthians", "2 Corinthians",
+	"Galatians", "Ephesians", "Philippians", "Colossians", "1 Thessalonians",
+	"2 Thessalonians", "1 Timothy", "2 Timothy", "Titus", "Philemon", "Hebrews", "James",
+	"1 Peter", "2 Peter", "1 John", "2 John", "3 John", "Jude", "Revelation",
+}
+
+// lastOldTestamentBook is the bookId of Malachi.
+const lastOldTestamentBook = 39
+
+// bookIDByName returns the bookId for an English book name, ignoring case.
+func bookIDByName(name string) (int, bool) {
+	for i, n := range bookNames {
+		if strings.EqualFold(n, strings.TrimSpace(name)) {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// TestBookTablesAgree checks the copies of the canon against each other:
+// books.go, data/metadata.json and scripts/fetch_data.go, which cannot import
+// books.go since it is built on its own.
+func TestBookTablesAgree(t *testing.T) {
+	if len(metadata.Books) != len(bookNames) {
+		t.Fatalf("metadata has %d books, books.go %d", len(metadata.Books), len(bookNames))
+	}
+	for i, book := range metadata.Books {
+		if book.ID != i+1 {
+			t.Errorf("metadata book %d has id %d", i+1, book.ID)
+		}
+		if book.Name != bookNames[i] {
+			t.Errorf("book %d: metadata names it %q, books.go %q", i+1, book.Name, bookNames[i])
+		}
+		if book.OSIS != osisBooks[i] {
+			t.Errorf("book %d: metadata has OSIS code %q, books.go %q", i+1, book.OSIS, osisBooks[i])
+		}
+		if book.Chapters != len(book.Verses) {
+			t.Errorf("%s: %d chapters but verse counts for %d", book.Name, book.Chapters, len(book.Verses))
+		}
+		want := "NT"
+		if book.ID <= lastOldTestamentBook {
+			want = "OT"
+		}
+		if book.Testament != want {
+			t.Errorf("%s: testament %q, want %q", book.Name, book.Testament, want)
+		}
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), "scripts/fetch_data.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var script []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok || spec.Names[0].Name != "osisBooks" {
+			return true
+		}
+		for _, elt := range spec.Values[0].(*ast.CompositeLit).Elts {
+			code, err := strconv.Unquote(elt.(*ast.BasicLit).Value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			script = append(script, code)
+		}
+		return false
+	})
+	if !slices.Equal(script, osisBooks[:]) {
+		t.Errorf("scripts/fetch_data.go osisBooks = %v, want %v", script, osisBooks)
+	}
+}
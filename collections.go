@@ -0,0 +1,446 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxCollectionNameLength bounds collection names, which are shown as
+// headings.
+const maxCollectionNameLength = 100
+
+// maxCollectionBodyBytes bounds the body of requests that create or change a
+// collection or add a verse to one. Only a description can be long.
+const maxCollectionBodyBytes = 64 << 10
+
+// Collection is a named list of verses, such as "Memory Verses", which
+// unlike a tag can hold verses nobody has highlighted.
+type Collection struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ItemCount   int    `json:"itemCount"`
+	CreatedAt   string `json:"createdAt"`
+	UpdatedAt   string `json:"updatedAt"`
+}
+
+// CollectionItem is a verse in a collection. Text is that verse in the
+// translation asked for, where it has been stored.
+type CollectionItem struct {
+	ID        int64  `json:"id"`
+	BookID    int    `json:"bookId"`
+	Chapter   int    `json:"chapter"`
+	Verse     int    `json:"verse"`
+	VerseID   string `json:"verseId"`
+	Reference string `json:"reference"`
+	OSISRef   string `json:"osisRef"`
+	Text      string `json:"text,omitempty"`
+	AddedAt   string `json:"addedAt"`
+}
+
+const collectionColumns = `id, name, description, (SELECT COUNT(*) FROM collection_items i WHERE i.collectionId = collections.id), createdAt, updatedAt`
+
+func scanCollection(s rowScanner) (Collection, error) {
+	var c Collection
+	err := s.Scan(&c.ID, &c.Name, &c.Description, &c.ItemCount, &c.CreatedAt, &c.UpdatedAt)
+	return c, err
+}
+
+func collectionsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		listCollectionsHandler(w, r)
+	case http.MethodPost:
+		createCollectionHandler(w, r)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// collectionHandler routes requests for a single collection addressed as
+// /api/collections/{id}, its items at /api/collections/{id}/items and one
+// item at /api/collections/{id}/items/{itemId}.
+func collectionHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/collections/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid collection ID")
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		switch r.Method {
+		case http.MethodGet:
+			getCollectionHandler(w, r, id)
+		case http.MethodPut:
+			updateCollectionHandler(w, r, id)
+		case http.MethodDelete:
+			deleteCollectionHandler(w, r, id)
+		default:
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	case len(parts) == 2 && parts[1] == "items":
+		switch r.Method {
+		case http.MethodGet:
+			listCollectionItemsHandler(w, r, id)
+		case http.MethodPost:
+			addCollectionItemHandler(w, r, id)
+		default:
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	case len(parts) == 3 && parts[1] == "items":
+		itemID, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid item ID")
+			return
+		}
+		if r.Method != http.MethodDelete {
+			writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		removeCollectionItemHandler(w, r, id, itemID)
+	default:
+		apiNotFoundHandler(w, r)
+	}
+}
+
+// decodeCollection reads the name and description of a collection from the
+// request body. It writes the error response itself and returns false if the
+// body is unreadable or the name is missing or too long.
+func decodeCollection(w http.ResponseWriter, r *http.Request) (Collection, bool) {
+	var c Collection
+	r.Body = http.MaxBytesReader(w, r.Body, maxCollectionBodyBytes)
+	err := json.NewDecoder(r.Body).Decode(&c)
+	if isBodyTooLarge(err) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, bodyTooLargeMessage(maxCollectionBodyBytes))
+		return c, false
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return c, false
+	}
+	c.Name = strings.TrimSpace(c.Name)
+	c.Description = strings.TrimSpace(c.Description)
+	if c.Name == "" {
+		writeJSONError(w, http.StatusBadRequest, "name is required")
+		return c, false
+	}
+	if len(c.Name) > maxCollectionNameLength {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("name must be at most %d bytes", maxCollectionNameLength))
+		return c, false
+	}
+	return c, true
+}
+
+// listCollectionsHandler returns the user's collections in name order,
+// without their items.
+func listCollectionsHandler(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.QueryContext(r.Context(), `SELECT `+collectionColumns+` FROM collections WHERE userId = ? ORDER BY name, id`,
+		userIDFromContext(r.Context()))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	collections := []Collection{}
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		collections = append(collections, c)
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(collections)
+}
+
+// createCollectionHandler stores a new, empty collection. Names are unique
+// per user, ignoring case.
+func createCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	c, ok := decodeCollection(w, r)
+	if !ok {
+		return
+	}
+	c.CreatedAt = timestamp()
+	c.UpdatedAt = c.CreatedAt
+
+	result, err := db.ExecContext(r.Context(), `INSERT INTO collections (userId, name, description, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?)`,
+		userIDFromContext(r.Context()), c.Name, c.Description, c.CreatedAt, c.UpdatedAt)
+	if isUniqueViolation(err) {
+		writeJSONError(w, http.StatusConflict, fmt.Sprintf("A collection named %q already exists", c.Name))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	c.ID, _ = result.LastInsertId()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(c)
+}
+
+// getCollectionHandler returns a collection together with its verses in
+// canonical order. translation, when given, adds the text of each verse.
+func getCollectionHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	c, err := scanCollection(db.QueryRowContext(r.Context(), `SELECT `+collectionColumns+` FROM collections WHERE id = ? AND userId = ?`,
+		id, userIDFromContext(r.Context())))
+	if err == sql.ErrNoRows {
+		writeJSONError(w, http.StatusNotFound, "Collection not found")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	items, err := collectionItems(r.Context(), id, r.URL.Query().Get("translation"))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Collection
+		Items []CollectionItem `json:"items"`
+	}{c, items})
+}
+
+// updateCollectionHandler renames a collection and replaces its description.
+func updateCollectionHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	c, ok := decodeCollection(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := db.ExecContext(r.Context(), `UPDATE collections SET name = ?, description = ?, updatedAt = ? WHERE id = ? AND userId = ?`,
+		c.Name, c.Description, timestamp(), id, userIDFromContext(r.Context()))
+	if isUniqueViolation(err) {
+		writeJSONError(w, http.StatusConflict, fmt.Sprintf("A collection named %q already exists", c.Name))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		writeJSONError(w, http.StatusNotFound, "Collection not found")
+		return
+	}
+
+	getCollectionHandler(w, r, id)
+}
+
+// deleteCollectionHandler deletes a collection; its items go with it.
+func deleteCollectionHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	result, err := db.ExecContext(r.Context(), `DELETE FROM collections WHERE id = ? AND userId = ?`, id, userIDFromContext(r.Context()))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		writeJSONError(w, http.StatusNotFound, "Collection not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// collectionItems returns the verses in a collection in canonical order,
+// with their text in translation if it is not empty.
+func collectionItems(ctx context.Context, collectionID int64, translation string) ([]CollectionItem, error) {
+	rows, err := db.QueryContext(ctx, `SELECT i.id, i.bookId, i.chapter, i.verse, COALESCE(v.text, ''), i.addedAt
+	                                   FROM collection_items i
+	                                   LEFT JOIN verses v ON v.translation = ? AND v.bookId = i.bookId AND v.chapter = i.chapter AND v.verse = i.verse
+	                                   WHERE i.collectionId = ?
+	                                   ORDER BY i.bookId, i.chapter, i.verse`, translation, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []CollectionItem{}
+	for rows.Next() {
+		var it CollectionItem
+		if err := rows.Scan(&it.ID, &it.BookID, &it.Chapter, &it.Verse, &it.Text, &it.AddedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, describeCollectionItem(it))
+	}
+	return items, rows.Err()
+}
+
+// describeCollectionItem fills in the ways of referring to an item's verse.
+func describeCollectionItem(it CollectionItem) CollectionItem {
+	it.VerseID = verseID(it.BookID, it.Chapter, it.Verse)
+	it.Reference = fmt.Sprintf("%s %d:%d", bookName(it.BookID), it.Chapter, it.Verse)
+	it.OSISRef = toOSIS(it.BookID, it.Chapter, it.Verse)
+	return it
+}
+
+// ownsCollection reports whether the collection exists and belongs to the
+// current user.
+func ownsCollection(ctx context.Context, id int64) (bool, error) {
+	var n int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM collections WHERE id = ? AND userId = ?`, id, userIDFromContext(ctx)).Scan(&n)
+	return n > 0, err
+}
+
+// listCollectionItemsHandler returns just the verses of a collection, as
+// getCollectionHandler does.
+func listCollectionItemsHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	owned, err := ownsCollection(r.Context(), id)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if !owned {
+		writeJSONError(w, http.StatusNotFound, "Collection not found")
+		return
+	}
+
+	items, err := collectionItems(r.Context(), id, r.URL.Query().Get("translation"))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// addCollectionItemHandler adds a verse, given as bookId, chapter and verse,
+// to a collection. Verses are checked against the bundled metadata, and a
+// verse can only be in a collection once.
+func addCollectionItemHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	var it CollectionItem
+	var body struct {
+		BookID  int `json:"bookId"`
+		Chapter int `json:"chapter"`
+		Verse   int `json:"verse"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxCollectionBodyBytes)
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if isBodyTooLarge(err) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, bodyTooLargeMessage(maxCollectionBodyBytes))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	it.BookID, it.Chapter, it.Verse = body.BookID, body.Chapter, body.Verse
+	lastVerse := metadataVerseCount(it.BookID, it.Chapter)
+	if lastVerse == 0 {
+		writeJSONError(w, http.StatusBadRequest, "bookId and chapter must name a chapter that exists")
+		return
+	}
+	if it.Verse < 1 || it.Verse > lastVerse {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("verse must be between 1 and %d", lastVerse))
+		return
+	}
+
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to begin transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	// The SELECT inserts nothing unless the collection is the user's.
+	it.AddedAt = timestamp()
+	result, err := tx.ExecContext(r.Context(), `INSERT INTO collection_items (collectionId, bookId, chapter, verse, addedAt)
+	                                            SELECT id, ?, ?, ?, ? FROM collections WHERE id = ? AND userId = ?`,
+		it.BookID, it.Chapter, it.Verse, it.AddedAt, id, userIDFromContext(r.Context()))
+	if isUniqueViolation(err) {
+		writeJSONError(w, http.StatusConflict, "That verse is already in the collection")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		writeJSONError(w, http.StatusNotFound, "Collection not found")
+		return
+	}
+	it.ID, _ = result.LastInsertId()
+	if _, err := tx.ExecContext(r.Context(), `UPDATE collections SET updatedAt = ? WHERE id = ?`, it.AddedAt, id); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to commit transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(describeCollectionItem(it))
+}
+
+// removeCollectionItemHandler takes a verse out of a collection.
+func removeCollectionItemHandler(w http.ResponseWriter, r *http.Request, id, itemID int64) {
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to begin transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	result, err := tx.ExecContext(r.Context(), `DELETE FROM collection_items
+	                                            WHERE id = ? AND collectionId = (SELECT id FROM collections WHERE id = ? AND userId = ?)`,
+		itemID, id, userIDFromContext(r.Context()))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		writeJSONError(w, http.StatusNotFound, "Item not found")
+		return
+	}
+	if _, err := tx.ExecContext(r.Context(), `UPDATE collections SET updatedAt = ? WHERE id = ?`, timestamp(), id); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to commit transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
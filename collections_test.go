@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCollections(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	mux.HandleFunc("/api/collections", requireUser(collectionsHandler))
+	mux.HandleFunc("/api/collections/", requireUser(collectionHandler))
+	ann := signUp(t, mux, "ann")
+	bob := signUp(t, mux, "bob")
+
+	contains := func(want ...string) func(t *testing.T, body string) {
+		return func(t *testing.T, body string) {
+			for _, w := range want {
+				if !strings.Contains(body, w) {
+					t.Errorf("body %s does not contain %s", body, w)
+				}
+			}
+		}
+	}
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		cookie *http.Cookie
+		want   int
+		check  func(t *testing.T, body string)
+	}{
+		{"create", http.MethodPost, "/api/collections", `{"name":" Memory Verses ","description":"For Sunday"}`, ann, http.StatusCreated, contains(`"id":1`, `"name":"Memory Verses"`)},
+		{"create same name in another case", http.MethodPost, "/api/collections", `{"name":"memory verses"}`, ann, http.StatusConflict, nil},
+		{"create same name as another user", http.MethodPost, "/api/collections", `{"name":"Memory Verses"}`, bob, http.StatusCreated, nil},
+		{"create without a name", http.MethodPost, "/api/collections", `{"description":"x"}`, ann, http.StatusBadRequest, nil},
+		{"create with an oversized body", http.MethodPost, "/api/collections", `{"name":"Long","description":"` + strings.Repeat("x", maxCollectionBodyBytes) + `"}`, ann, http.StatusRequestEntityTooLarge, nil},
+		{"add John 3:16", http.MethodPost, "/api/collections/1/items", `{"bookId":43,"chapter":3,"verse":16}`, ann, http.StatusCreated, contains(`"verseId":"verse-43-3-16"`, `"reference":"John 3:16"`, `"osisRef":"John.3.16"`)},
+		{"add Genesis 1:1", http.MethodPost, "/api/collections/1/items", `{"bookId":1,"chapter":1,"verse":1}`, ann, http.StatusCreated, nil},
+		{"add a verse twice", http.MethodPost, "/api/collections/1/items", `{"bookId":43,"chapter":3,"verse":16}`, ann, http.StatusConflict, nil},
+		{"add a verse past the chapter", http.MethodPost, "/api/collections/1/items", `{"bookId":43,"chapter":3,"verse":37}`, ann, http.StatusBadRequest, nil},
+		{"add with an oversized body", http.MethodPost, "/api/collections/1/items", `{"bookId":43,"chapter":3,"verse":16,"note":"` + strings.Repeat("x", maxCollectionBodyBytes) + `"}`, ann, http.StatusRequestEntityTooLarge, nil},
+		{"add to another user's collection", http.MethodPost, "/api/collections/1/items", `{"bookId":19,"chapter":23,"verse":1}`, bob, http.StatusNotFound, nil},
+		{"get in canonical order", http.MethodGet, "/api/collections/1", "", ann, http.StatusOK, func(t *testing.T, body string) {
+			contains(`"itemCount":2`)(t, body)
+			if strings.Index(body, "verse-1-1-1") > strings.Index(body, "verse-43-3-16") {
+				t.Errorf("body %s lists John before Genesis", body)
+			}
+		}},
+		{"get as another user", http.MethodGet, "/api/collections/1", "", bob, http.StatusNotFound, nil},
+		{"list", http.MethodGet, "/api/collections", "", ann, http.StatusOK, func(t *testing.T, body string) {
+			if strings.Count(body, `"id":`) != 1 {
+				t.Errorf("body %s does not list only ann's collection", body)
+			}
+		}},
+		{"rename", http.MethodPut, "/api/collections/1", `{"name":"Favorites"}`, ann, http.StatusOK, contains(`"name":"Favorites"`, `"description":""`)},
+		{"remove an item", http.MethodDelete, "/api/collections/1/items/1", "", ann, http.StatusNoContent, nil},
+		{"remove it again", http.MethodDelete, "/api/collections/1/items/1", "", ann, http.StatusNotFound, nil},
+		{"items after removal", http.MethodGet, "/api/collections/1/items", "", ann, http.StatusOK, func(t *testing.T, body string) {
+			if strings.Contains(body, "verse-43-3-16") || !strings.Contains(body, "verse-1-1-1") {
+				t.Errorf("body %s, want only Genesis 1:1", body)
+			}
+		}},
+		{"invalid ID", http.MethodGet, "/api/collections/one", "", ann, http.StatusBadRequest, nil},
+		{"delete as another user", http.MethodDelete, "/api/collections/1", "", bob, http.StatusNotFound, nil},
+		{"delete", http.MethodDelete, "/api/collections/1", "", ann, http.StatusNoContent, nil},
+		{"get after delete", http.MethodGet, "/api/collections/1/items", "", ann, http.StatusNotFound, nil},
+	}
+	// The cases build on each other, so they run in order against one database.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(mux, tt.method, tt.target, tt.body, tt.cookie)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.check != nil {
+				tt.check(t, rec.Body.String())
+			}
+		})
+	}
+
+	var items int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM collection_items WHERE collectionId = 1`).Scan(&items); err != nil || items != 0 {
+		t.Errorf("deleted collection left %d items, err %v", items, err)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// hexColorPattern matches a #rrggbb color, in either case.
+var hexColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// namedColors are the color names accepted in place of a hex value, with the
+// CSS color each one stands for.
+var namedColors = map[string]string{
+	"yellow": "#ffff00",
+	"green":  "#008000",
+	"blue":   "#0000ff",
+	"red":    "#ff0000",
+	"orange": "#ffa500",
+	"pink":   "#ffc0cb",
+	"purple": "#800080",
+	"gray":   "#808080",
+	"grey":   "#808080",
+}
+
+// normalizeColor turns a highlight color into the lowercase #rrggbb form that
+// is stored. It accepts a hex value or one of namedColors, ignoring case and
+// surrounding space, and passes "" through for a highlight that uses the
+// default color. Anything else is an error worded for the client.
+func normalizeColor(color string) (string, error) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	if color == "" || hexColorPattern.MatchString(color) {
+		return color, nil
+	}
+	if hex, ok := namedColors[color]; ok {
+		return hex, nil
+	}
+	return "", fmt.Errorf("Invalid color %q; use #rrggbb or one of: %s", color, strings.Join(slices.Sorted(maps.Keys(namedColors)), ", "))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeColor(t *testing.T) {
+	tests := []struct {
+		color   string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"#ffff00", "#ffff00", false},
+		{"#FFaa00", "#ffaa00", false},
+		{"  #abcdef\n", "#abcdef", false},
+		{"yellow", "#ffff00", false},
+		{"Grey", "#808080", false},
+		{" BLUE ", "#0000ff", false},
+		{"#fff", "", true},
+		{"#ffff000", "", true},
+		{"ffff00", "", true},
+		{"#gggggg", "", true},
+		{"chartreuse", "", true},
+		{"rgb(255, 255, 0)", "", true},
+		{"yellow; background: url(x)", "", true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeColor(tt.color)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("normalizeColor(%q) = %q, %v; want %q, error %t", tt.color, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCreateNormalizesColor(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+
+	named := strings.Replace(testHighlight("named"), `"#ffff00"`, `"Yellow"`, 1)
+	if rec := serve(mux, http.MethodPost, "/api/highlights", named, ann); rec.Code != http.StatusCreated {
+		t.Fatalf("create with a named color: status %d: %s", rec.Code, rec.Body)
+	}
+	rec := serve(mux, http.MethodGet, "/api/highlights/named", "", ann)
+	if !strings.Contains(rec.Body.String(), `"color":"#ffff00"`) {
+		t.Errorf("named color was not stored as hex: %s", rec.Body)
+	}
+
+	bogus := strings.Replace(testHighlight("bogus"), `"#ffff00"`, `"chartreuse"`, 1)
+	if rec := serve(mux, http.MethodPost, "/api/highlights", bogus, ann); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("create with an unknown color: status %d, want 422: %s", rec.Code, rec.Body)
+	}
+}
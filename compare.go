@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// ComparedVerse holds the highlights on one verse in each of the two
+// translations being compared.
+type ComparedVerse struct {
+	Verse int         `json:"verse"`
+	A     []Highlight `json:"a"`
+	B     []Highlight `json:"b"`
+}
+
+// Comparison is the body of /api/highlights/compare.
+type Comparison struct {
+	BookID  int             `json:"bookId"`
+	Chapter int             `json:"chapter"`
+	A       string          `json:"a"`
+	B       string          `json:"b"`
+	Verses  []ComparedVerse `json:"verses"`
+}
+
+// compareHighlightsHandler returns the user's highlights in one chapter in
+// two translations, a and b, grouped by verse so they can be shown in
+// parallel columns. Only verses highlighted in at least one of them are
+// listed, in order. A span is listed under the verse it starts on, or verse
+// 1 if it starts in an earlier chapter.
+func compareHighlightsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	q := r.URL.Query()
+	c := Comparison{A: q.Get("a"), B: q.Get("b"), Verses: []ComparedVerse{}}
+	if c.A == "" || c.B == "" || q.Get("bookId") == "" || q.Get("chapter") == "" {
+		writeJSONError(w, http.StatusBadRequest, "Missing required query parameters: bookId, chapter, a, b")
+		return
+	}
+	var err error
+	if c.BookID, err = strconv.Atoi(q.Get("bookId")); err != nil || c.BookID < 1 {
+		writeJSONError(w, http.StatusBadRequest, "bookId must be a positive integer")
+		return
+	}
+	if c.Chapter, err = strconv.Atoi(q.Get("chapter")); err != nil || c.Chapter < 1 {
+		writeJSONError(w, http.StatusBadRequest, "chapter must be a positive integer")
+		return
+	}
+
+	userID := userIDFromContext(r.Context())
+	a, err := chapterHighlightsByVerse(r.Context(), userID, c.A, c.BookID, c.Chapter)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	b, err := chapterHighlightsByVerse(r.Context(), userID, c.B, c.BookID, c.Chapter)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	c.Verses = compareVerses(a, b)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+// chapterHighlightsByVerse returns the user's highlights in a chapter of one
+// translation, keyed by the verse each is listed under.
+func chapterHighlightsByVerse(ctx context.Context, userID int64, translation string, bookID, chapter int) (map[int][]Highlight, error) {
+	query := `SELECT ` + highlightColumns + ` FROM highlights
+	          WHERE userId = ? AND translation = ? AND bookId = ? AND chapter <= ? AND endChapter >= ? AND deletedAt IS NULL
+	          ORDER BY chapter, ` + verseNumberSQL + `, start, rowid`
+	rows, err := db.QueryContext(ctx, query, userID, translation, bookID, chapter, chapter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byVerse := map[int][]Highlight{}
+	for rows.Next() {
+		h, err := scanHighlight(rows)
+		if err != nil {
+			return nil, err
+		}
+		verse := 1
+		if _, c, v, ok := parseVerseID(h.VerseID); ok && c == chapter {
+			verse = v
+		}
+		byVerse[verse] = append(byVerse[verse], h)
+	}
+	return byVerse, rows.Err()
+}
+
+// compareVerses pairs up the highlights of two translations verse by verse,
+// in verse order.
+func compareVerses(a, b map[int][]Highlight) []ComparedVerse {
+	numbers := slices.Collect(maps.Keys(a))
+	for v := range b {
+		if _, ok := a[v]; !ok {
+			numbers = append(numbers, v)
+		}
+	}
+	slices.Sort(numbers)
+
+	verses := make([]ComparedVerse, len(numbers))
+	for i, v := range numbers {
+		verses[i] = ComparedVerse{Verse: v, A: a[v], B: b[v]}
+		if verses[i].A == nil {
+			verses[i].A = []Highlight{}
+		}
+		if verses[i].B == nil {
+			verses[i].B = []Highlight{}
+		}
+	}
+	return verses
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// CopyRequest is the body of /api/highlights/copy.
+type CopyRequest struct {
+	FromTranslation string `json:"fromTranslation"`
+	ToTranslation   string `json:"toTranslation"`
+	BookID          int    `json:"bookId"`
+	Chapter         int    `json:"chapter"`
+}
+
+// CopyResult reports how many highlights /api/highlights/copy created, and
+// which of the source highlights it left out because they were copied
+// before or their color does not match the palette of the destination
+// translation.
+type CopyResult struct {
+	Copied  int          `json:"copied"`
+	Skipped []ImportSkip `json:"skipped"`
+}
+
+// copiedHighlightID returns the ID of the copy of the highlight sourceID in
+// translation. It is the same every time, so copying a chapter again finds
+// the copies already made instead of duplicating them.
+func copiedHighlightID(sourceID, translation string) string {
+	sum := sha256.Sum256([]byte(sourceID + "|" + translation))
+	return "c-" + hex.EncodeToString(sum[:8])
+}
+
+// copyHighlightsHandler duplicates the user's highlights that start in a
+// chapter from one translation into another. A span reaching in from an
+// earlier chapter is copied with that chapter instead. Character offsets do
+// not carry over between translations, so each copy covers its whole verse
+// (start and end of 0) and is flagged needsReview until the user adjusts it.
+// Highlights copied before, even if the copy is now in the trash, are
+// skipped, as are those whose color does not match the destination's
+// palette, which applies as when creating a highlight; both are reported.
+func copyHighlightsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req CopyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.FromTranslation == "" || req.ToTranslation == "" || req.BookID < 1 || req.Chapter < 1 {
+		writeJSONError(w, http.StatusBadRequest, "fromTranslation, toTranslation, bookId and chapter are required")
+		return
+	}
+	if req.FromTranslation == req.ToTranslation {
+		writeJSONError(w, http.StatusBadRequest, "fromTranslation and toTranslation must differ")
+		return
+	}
+
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to begin transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	query := `SELECT ` + highlightColumns + ` FROM highlights
+	          WHERE userId = ? AND translation = ? AND bookId = ? AND chapter = ? AND deletedAt IS NULL
+	          ORDER BY rowid`
+	rows, err := tx.QueryContext(r.Context(), query, userIDFromContext(r.Context()), req.FromTranslation, req.BookID, req.Chapter)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	var copies []Highlight
+	for rows.Next() {
+		h, err := scanHighlight(rows)
+		if err != nil {
+			rows.Close()
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		copies = append(copies, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	stmt := tx.StmtContext(r.Context(), insertHighlightStmt)
+	defer stmt.Close()
+
+	result := CopyResult{Skipped: []ImportSkip{}}
+	var copied []Highlight
+	for i := range copies {
+		h := &copies[i]
+		sourceID := h.ID
+		h.ID = copiedHighlightID(sourceID, req.ToTranslation)
+		err := tx.QueryRowContext(r.Context(), `SELECT 1 FROM highlights WHERE id = ?`, h.ID).Scan(new(int))
+		if err == nil {
+			result.Skipped = append(result.Skipped, ImportSkip{Index: i, Reason: fmt.Sprintf("%s: already copied as %s", sourceID, h.ID)})
+			continue
+		}
+		if err != sql.ErrNoRows {
+			writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+			slog.Error("Database error", "err", err)
+			return
+		}
+
+		h.UserID = userIDFromContext(r.Context())
+		h.Translation = req.ToTranslation
+		msg, err := applyPalette(r.Context(), tx, h)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		if msg != "" {
+			result.Skipped = append(result.Skipped, ImportSkip{Index: i, Reason: fmt.Sprintf("%s: %s", sourceID, msg)})
+			continue
+		}
+
+		h.Start, h.End = 0, 0
+		h.NeedsReview = true
+		h.CreatedAt = ""
+		stampHighlight(h)
+
+		if _, err := stmt.ExecContext(r.Context(), insertHighlightArgs(*h)...); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		if err := setHighlightTags(r.Context(), tx, *h); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to save tags")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		copied = append(copied, *h)
+	}
+
+	if err := tx.Commit(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to commit transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	for _, h := range copied {
+		hub.publishHighlight("created", h)
+	}
+	result.Copied = len(copied)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCopyTakesEachHighlightOnce(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+
+	// Genesis 1:31-2:3 starts in chapter 1 and shows in chapter 2 as well.
+	span := strings.Replace(testHighlight("span"), `"verseId":"verse-1-1-1"`, `"verseId":"verse-1-1-31","endVerseId":"verse-1-2-3"`, 1)
+	in2 := strings.NewReplacer(`"verse-1-1-1"`, `"verse-1-2-4"`, `"chapter":1`, `"chapter":2`).Replace(testHighlight("in-2"))
+	for _, body := range []string{span, in2} {
+		if rec := serve(mux, http.MethodPost, "/api/highlights", body, ann); rec.Code != http.StatusCreated {
+			t.Fatalf("create: status %d: %s", rec.Code, rec.Body)
+		}
+	}
+
+	copyChapter := func(chapter string) string {
+		return `{"fromTranslation":"KJV","toTranslation":"WEB","bookId":1,"chapter":` + chapter + `}`
+	}
+	tests := []struct {
+		name   string
+		body   string
+		copied int
+		reply  string // substring of the response
+	}{
+		{"chapter 1", copyChapter("1"), 1, `"skipped":[]`},
+		{"chapter 2 leaves the span to chapter 1", copyChapter("2"), 1, `"skipped":[]`},
+		{"chapter 1 again", copyChapter("1"), 0, `"reason":"span: already copied`},
+		{"chapter 2 again", copyChapter("2"), 0, `"reason":"in-2: already copied`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(mux, http.MethodPost, "/api/highlights/copy", tt.body, ann)
+			var got CopyResult
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decoding body: %v", err)
+			}
+			if rec.Code != http.StatusCreated || got.Copied != tt.copied || !strings.Contains(rec.Body.String(), tt.reply) {
+				t.Errorf("got %d %s, want %d copied and %s", rec.Code, rec.Body, tt.copied, tt.reply)
+			}
+		})
+	}
+
+	rec := serve(mux, http.MethodGet, "/api/highlights?translation=WEB&bookId=1", "", ann)
+	var got []Highlight
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("WEB has %d highlights, want one copy of each: %+v", len(got), got)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CrossReference is a verse related to the one requested.
+type CrossReference struct {
+	BookID     int `json:"bookId"`
+	Chapter    int `json:"chapter"`
+	Verse      int `json:"verse"`
+	EndChapter int `json:"endChapter,omitempty"`
+	EndVerse   int `json:"endVerse,omitempty"`
+}
+
+// osisRef is a parsed single-verse OSIS reference such as "John.3.16".
+type osisRef struct {
+	bookID, chapter, verse int
+}
+
+func parseOSISRef(s string) (osisRef, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return osisRef{}, fmt.Errorf("malformed reference %q", s)
+	}
+	bookID, ok := osisBookIDs[parts[0]]
+	if !ok {
+		return osisRef{}, fmt.Errorf("unknown book in reference %q", s)
+	}
+	chapter, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return osisRef{}, fmt.Errorf("malformed chapter in reference %q", s)
+	}
+	verse, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return osisRef{}, fmt.Errorf("malformed verse in reference %q", s)
+	}
+	return osisRef{bookID, chapter, verse}, nil
+}
+
+// loadCrossReferences seeds the cross_references table from the OpenBible.info
+// cross-reference dataset (derived from the Treasury of Scripture Knowledge),
+// available from https://www.openbible.info/labs/cross-references/. The file
+// is tab separated with a header row: from verse, to verse or range, votes.
+// It is only loaded into an empty table. An empty path leaves the table as it
+// is, but a missing file is an error so the endpoint does not quietly return
+// nothing; scripts/fetch_data.go downloads it.
+func loadCrossReferences(path string) error {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM cross_references`).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	if path == "" {
+		slog.Warn("No cross-reference data configured; /api/cross_references will return no results")
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w; run `go run scripts/fetch_data.go crossrefs` to download it, or pass -crossrefs= to run without cross references", err)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	stmt, err := tx.Prepare(`INSERT INTO cross_references (fromBookId, fromChapter, fromVerse, toBookId, toChapter, toVerse, endChapter, endVerse, votes)
+	                         VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Split(scanner.Text(), "\t")
+		if line == 1 || len(fields) < 2 {
+			continue // Header row or blank line
+		}
+
+		from, err := parseOSISRef(fields[0])
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		startRef, endRef, isRange := strings.Cut(fields[1], "-")
+		to, err := parseOSISRef(startRef)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		var endChapter, endVerse any
+		if isRange {
+			end, err := parseOSISRef(endRef)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			endChapter, endVerse = end.chapter, end.verse
+		}
+		votes := 0
+		if len(fields) > 2 {
+			votes, _ = strconv.Atoi(fields[2])
+		}
+
+		if _, err := stmt.Exec(from.bookID, from.chapter, from.verse, to.bookID, to.chapter, to.verse, endChapter, endVerse, votes); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// crossReferencesHandler lists the references for a verse, most relevant
+// first. A verse without references yields an empty array.
+func crossReferencesHandler(w http.ResponseWriter, r *http.Request) {
+	var ref [3]int
+	for i, name := range []string{"bookId", "chapter", "verse"} {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			writeJSONError(w, http.StatusBadRequest, "Missing required query parameters: bookId, chapter, verse")
+			return
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, name+" must be an integer")
+			return
+		}
+		ref[i] = n
+	}
+
+	query := `SELECT toBookId, toChapter, toVerse, endChapter, endVerse FROM cross_references
+	          WHERE fromBookId = ? AND fromChapter = ? AND fromVerse = ?
+	          ORDER BY votes DESC, toBookId, toChapter, toVerse`
+
+	rows, err := db.QueryContext(r.Context(), query, ref[0], ref[1], ref[2])
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	refs := []CrossReference{}
+	for rows.Next() {
+		var c CrossReference
+		var endChapter, endVerse *int
+		if err := rows.Scan(&c.BookID, &c.Chapter, &c.Verse, &endChapter, &endVerse); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		if endChapter != nil && endVerse != nil {
+			c.EndChapter, c.EndVerse = *endChapter, *endVerse
+		}
+		refs = append(refs, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(refs)
+}
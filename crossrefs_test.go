@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadCrossReferences(t *testing.T) {
+	useTestDB(t)
+	path := filepath.Join(t.TempDir(), "cross_references.txt")
+	data := "From Verse\tTo Verse\tVotes\n" +
+		"John.3.16\tRom.5.8\t120\n" +
+		"John.3.16\t1John.4.9-1John.4.10\t300\n" +
+		"Gen.1.1\tJohn.1.1\t50\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadCrossReferences(path); err != nil {
+		t.Fatalf("loadCrossReferences: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+		body  string
+	}{
+		{"ordered by votes, with ranges", "bookId=43&chapter=3&verse=16", http.StatusOK,
+			`[{"bookId":62,"chapter":4,"verse":9,"endChapter":4,"endVerse":10},{"bookId":45,"chapter":5,"verse":8}]` + "\n"},
+		{"verse without references", "bookId=43&chapter=3&verse=17", http.StatusOK, "[]\n"},
+		{"missing verse", "bookId=43&chapter=3", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(http.HandlerFunc(crossReferencesHandler), http.MethodGet, "/api/cross_references?"+tt.query, "", nil)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Errorf("body = %s, want %s", rec.Body, tt.body)
+			}
+		})
+	}
+
+	// A seeded table is kept, so the file is no longer needed.
+	if err := loadCrossReferences(filepath.Join(t.TempDir(), "gone.txt")); err != nil {
+		t.Errorf("reloading into a seeded table: %v", err)
+	}
+}
+
+func TestLoadCrossReferencesMissingFile(t *testing.T) {
+	useTestDB(t)
+	if err := loadCrossReferences(filepath.Join(t.TempDir(), "gone.txt")); err == nil {
+		t.Error("missing file was not an error")
+	}
+	if err := loadCrossReferences(""); err != nil {
+		t.Errorf("empty path: %v", err)
+	}
+}
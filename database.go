@@ -0,0 +1,58 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"bible_app/migrations"
+)
+
+// openDB opens the SQLite database at path, brings its schema up to date and
+// prepares the shared statements, leaving it ready to assign to db. It also
+// sets textSearchEnabled. path may be ":memory:" for a throwaway database,
+// such as in tests.
+func openDB(path string) (*sql.DB, error) {
+	// Foreign keys are off by default in SQLite; tag links rely on them to
+	// cascade when a highlight is deleted.
+	//
+	// Concurrent writes are left to SQLite rather than funnelled through one
+	// goroutine. WAL lets readers carry on while a write is in progress, a
+	// busy timeout makes a writer wait its turn instead of failing with
+	// "database is locked", and transactions take the write lock when they
+	// begin so that one reading before it writes cannot deadlock with another
+	// writer and fail regardless of the timeout.
+	conn, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	if path == ":memory:" {
+		// Every connection to :memory: gets its own empty database, so keep
+		// to one connection for the schema and data to stay visible.
+		conn.SetMaxOpenConns(1)
+	}
+
+	applied, err := migrations.Apply(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("migrating database: %w", err)
+	}
+	if applied > 0 {
+		slog.Info("Applied database migrations", "count", applied)
+	}
+
+	textSearchEnabled, err = setupTextSearch(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("setting up text search: %w", err)
+	}
+	if !textSearchEnabled {
+		slog.Warn("SQLite was built without FTS5; Bible text search is disabled")
+	}
+
+	if err := prepareStatements(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("preparing statements: %w", err)
+	}
+	return conn, nil
+}
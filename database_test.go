@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// insertTestHighlights stores n highlights for userID spread over 50 books of
+// 20 chapters each, in one transaction.
+func insertTestHighlights(t testing.TB, userID int64, n int) {
+	t.Helper()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	stmt := tx.Stmt(insertHighlightStmt)
+	now := timestamp()
+	for i := range n {
+		bookID, chapter := i%50+1, i/50%20+1
+		h := Highlight{
+			ID: fmt.Sprintf("h-%d", i), Type: "highlight-only", VerseID: verseID(bookID, chapter, 1), End: 5,
+			Translation: "KJV", BookID: bookID, Chapter: chapter, Color: "#ffff00", UserID: userID, CreatedAt: now, UpdatedAt: now,
+		}
+		if _, err := stmt.Exec(insertHighlightArgs(h)...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChapterLookupUsesIndex(t *testing.T) {
+	useTestDB(t)
+
+	rows, err := db.Query(`EXPLAIN QUERY PLAN SELECT `+highlightColumns+` FROM highlights
+	                       WHERE userId = ? AND translation = ? AND bookId = ? AND deletedAt IS NULL AND chapter <= ? AND endChapter >= ?`,
+		1, "KJV", 1, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var plan []string
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+			t.Fatal(err)
+		}
+		plan = append(plan, detail)
+	}
+	if joined := strings.Join(plan, "\n"); !strings.Contains(joined, "SEARCH highlights USING INDEX") {
+		t.Errorf("chapter lookup does not search an index of highlights:\n%s", joined)
+	}
+}
+
+func TestChapterLookupIsFastWithManyHighlights(t *testing.T) {
+	if testing.Short() {
+		t.Skip("inserts 50k rows")
+	}
+	useTestDB(t)
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+	insertTestHighlights(t, 1, 50_000)
+
+	// Generous enough for a slow CI machine; a full table scan of 50k rows
+	// for each of the 20 requests would take far longer.
+	const bound = 500 * time.Millisecond
+	start := time.Now()
+	for chapter := 1; chapter <= 20; chapter++ {
+		rec := serve(mux, http.MethodGet, fmt.Sprintf("/api/highlights?translation=KJV&bookId=7&chapter=%d", chapter), "", ann)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+		if n := strings.Count(rec.Body.String(), `"id":"h-`); n != 50 {
+			t.Fatalf("chapter %d: got %d highlights, want 50", chapter, n)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > bound {
+		t.Errorf("20 chapter lookups took %v, want under %v", elapsed, bound)
+	}
+}
+
+func BenchmarkChapterLookup(b *testing.B) {
+	conn, err := openDB(":memory:")
+	if err != nil {
+		b.Fatal(err)
+	}
+	old := db
+	db = conn
+	defer func() { closeStatements(); conn.Close(); db = old }()
+	insertTestHighlights(b, 1, 50_000)
+
+	b.ResetTimer()
+	for i := range b.N {
+		rows, err := db.Query(`SELECT `+highlightColumns+` FROM highlights
+		                       WHERE userId = ? AND translation = ? AND bookId = ? AND deletedAt IS NULL AND chapter <= ? AND endChapter >= ?`,
+			1, "KJV", i%50+1, 3, 3)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for rows.Next() {
+		}
+		rows.Close()
+	}
+}
+
+// TestConcurrentWrites hammers the write handlers from many goroutines at
+// once against a database file, where unlike :memory: each goroutine can get
+// its own connection and so contend for SQLite's write lock. Every request
+// must succeed: without the busy timeout some fail with "database is locked".
+func TestConcurrentWrites(t *testing.T) {
+	conn, err := openDB(filepath.Join(t.TempDir(), "bible.db"))
+	if err != nil {
+		t.Fatalf("openDB: %v", err)
+	}
+	old := db
+	db = conn
+	t.Cleanup(func() {
+		closeStatements()
+		conn.Close()
+		db = old
+	})
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+
+	const workers, perWorker = 16, 20
+	var wg sync.WaitGroup
+	errs := make(chan string, workers*perWorker*3)
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWorker {
+				id := fmt.Sprintf("w%d-%d", w, i)
+				steps := []struct {
+					method, target, body string
+				}{
+					{http.MethodPost, "/api/highlights", testHighlight(id)},
+					{http.MethodPut, "/api/highlights/" + id, `{"note":"edited by ` + id + `"}`},
+					{http.MethodDelete, "/api/highlights/delete/" + id, ""},
+				}
+				// Leave every other highlight in place so the chapter keeps
+				// growing while the others write.
+				if i%2 == 0 {
+					steps = steps[:2]
+				}
+				for _, s := range steps {
+					if rec := serve(mux, s.method, s.target, s.body, ann); rec.Code >= http.StatusMultipleChoices {
+						errs <- fmt.Sprintf("%s %s: status %d: %s", s.method, s.target, rec.Code, rec.Body)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for msg := range errs {
+		t.Error(msg)
+	}
+
+	var live int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM highlights WHERE deletedAt IS NULL`).Scan(&live); err != nil {
+		t.Fatal(err)
+	}
+	if want := workers * perWorker / 2; live != want {
+		t.Errorf("%d highlights left, want %d", live, want)
+	}
+}
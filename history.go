@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// historySuffix ends the path of a highlight's note history endpoint.
+const historySuffix = "/history"
+
+// NoteRevision is a note a highlight used to have, and when it was replaced.
+type NoteRevision struct {
+	Note       string `json:"note"`
+	ReplacedAt string `json:"replacedAt"`
+}
+
+// recordNoteHistory saves the current note of the highlight id as a revision
+// ahead of it being replaced by note. Nothing is saved when the highlight has
+// no note, when it is not the user's, or when the note is not changing.
+func recordNoteHistory(ctx context.Context, tx *sql.Tx, id string, userID int64, note sql.NullString, replacedAt string) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO highlight_note_history (highlightId, userId, note, replacedAt)
+	                               SELECT id, userId, note, ? FROM highlights
+	                               WHERE id = ? AND userId = ? AND deletedAt IS NULL AND note IS NOT NULL AND note IS NOT ?`,
+		replacedAt, id, userID, note)
+	return err
+}
+
+// noteHistoryHandler returns the earlier notes of a highlight, newest first.
+// The current note is not included; it is on the highlight itself.
+func noteHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := highlightIDFromPathSegment(r, "/api/highlights/", historySuffix)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var exists bool
+	err = db.QueryRowContext(r.Context(), `SELECT EXISTS (SELECT 1 FROM highlights WHERE id = ? AND userId = ? AND deletedAt IS NULL)`,
+		id, userIDFromContext(r.Context())).Scan(&exists)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if !exists {
+		writeJSONError(w, http.StatusNotFound, "Highlight not found")
+		return
+	}
+
+	rows, err := db.QueryContext(r.Context(), `SELECT note, replacedAt FROM highlight_note_history
+	                                           WHERE highlightId = ? AND userId = ? ORDER BY id DESC`, id, userIDFromContext(r.Context()))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	revisions := []NoteRevision{}
+	for rows.Next() {
+		var rev NoteRevision
+		if err := rows.Scan(&rev.Note, &rev.ReplacedAt); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
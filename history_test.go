@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNoteHistoryStaysWithItsOwner(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+	bob := signUp(t, mux, "bob")
+
+	if rec := serve(mux, http.MethodPost, "/api/highlights", testHighlight("h-1"), ann); rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", rec.Code, rec.Body)
+	}
+	for _, note := range []string{"first", "second"} {
+		if rec := serve(mux, http.MethodPut, "/api/highlights/h-1", `{"note":"`+note+`"}`, ann); rec.Code != http.StatusOK {
+			t.Fatalf("update: status %d: %s", rec.Code, rec.Body)
+		}
+	}
+	if rec := serve(mux, http.MethodGet, "/api/highlights/h-1/history", "", ann); !strings.Contains(rec.Body.String(), `"note":"first"`) {
+		t.Fatalf("history before purge: status %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := serve(mux, http.MethodDelete, "/api/highlights/delete/h-1", "", ann); rec.Code != http.StatusOK {
+		t.Fatalf("delete: status %d: %s", rec.Code, rec.Body)
+	}
+	if n, err := purgeTrash(context.Background(), db, time.Now().Add(trashRetention+time.Hour)); err != nil || n != 1 {
+		t.Fatalf("purgeTrash = %d, %v; want 1", n, err)
+	}
+	var left int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM highlight_note_history`).Scan(&left); err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Errorf("%d revisions outlived the purged highlight", left)
+	}
+
+	// Another user taking the freed ID starts with no history, even if some
+	// was left behind.
+	if _, err := db.Exec(`INSERT INTO highlight_note_history (highlightId, userId, note, replacedAt) VALUES ('h-1', 1, 'stale', ?)`, timestamp()); err != nil {
+		t.Fatal(err)
+	}
+	if rec := serve(mux, http.MethodPost, "/api/highlights", testHighlight("h-1"), bob); rec.Code != http.StatusCreated {
+		t.Fatalf("create as bob: status %d: %s", rec.Code, rec.Body)
+	}
+	rec := serve(mux, http.MethodGet, "/api/highlights/h-1/history", "", bob)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("bob's history: status %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// HighlightEvent is pushed to connected clients when highlights change.
+// "created" and "updated" carry the highlight, "deleted" carries its ID, and
+// "cleared" means every highlight in the chapter was removed.
+type HighlightEvent struct {
+	Type      string     `json:"type"`
+	ID        string     `json:"id,omitempty"`
+	Highlight *Highlight `json:"highlight,omitempty"`
+}
+
+// chapterKey identifies whose highlights in which chapter a client is
+// watching. Events are only delivered to clients with the same key.
+type chapterKey struct {
+	userID      int64
+	translation string
+	bookID      int
+	chapter     int
+}
+
+// chapterKeysOf returns the key of every chapter h shows in, from the one it
+// starts in to the one a span ends in.
+func chapterKeysOf(h Highlight) []chapterKey {
+	var keys []chapterKey
+	for c := h.Chapter; c <= max(h.Chapter, highlightEndChapter(h)); c++ {
+		keys = append(keys, chapterKey{h.UserID, h.Translation, h.BookID, c})
+	}
+	return keys
+}
+
+// hubClientBuffer is how many events may queue for a client before it is
+// considered too slow and disconnected.
+const hubClientBuffer = 32
+
+type hubClient struct {
+	key  chapterKey
+	send chan HighlightEvent
+}
+
+// highlightHub fans highlight events out to the WebSocket clients watching
+// the affected chapter, for example the same user reading on a second device.
+type highlightHub struct {
+	mu      sync.Mutex
+	clients map[*hubClient]struct{}
+}
+
+var hub = &highlightHub{clients: map[*hubClient]struct{}{}}
+
+func (h *highlightHub) register(key chapterKey) *hubClient {
+	c := &hubClient{key: key, send: make(chan HighlightEvent, hubClientBuffer)}
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+	return c
+}
+
+func (h *highlightHub) unregister(c *hubClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c]; ok {
+		delete(h.clients, c)
+		close(c.send)
+	}
+}
+
+// publish queues ev for every client watching key. It never blocks: a client
+// whose buffer is full is dropped and will not receive further events.
+func (h *highlightHub) publish(key chapterKey, ev HighlightEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		if c.key != key {
+			continue
+		}
+		select {
+		case c.send <- ev:
+		default:
+			delete(h.clients, c)
+			close(c.send)
+		}
+	}
+}
+
+// publishHighlight sends a "created" or "updated" event for hl to every
+// chapter it shows in.
+func (h *highlightHub) publishHighlight(eventType string, hl Highlight) {
+	for _, key := range chapterKeysOf(hl) {
+		h.publish(key, HighlightEvent{Type: eventType, ID: hl.ID, Highlight: &hl})
+	}
+}
+
+// publishDeleted sends a "deleted" event for hl to every chapter it showed in.
+func (h *highlightHub) publishDeleted(hl Highlight) {
+	for _, key := range chapterKeysOf(hl) {
+		h.publish(key, HighlightEvent{Type: "deleted", ID: hl.ID})
+	}
+}
+
+// highlightsSocketHandler upgrades to a WebSocket that streams
+// HighlightEvents for the chapter named by the translation, bookId and
+// chapter query parameters. Because the session cookie authenticates the
+// socket, the handshake is refused for pages on other origins unless they
+// are in allowedOrigins.
+func highlightsSocketHandler(allowedOrigins []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		translation, bookID, chapter, err := parseChapterQuery(r)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		key := chapterKey{userIDFromContext(r.Context()), translation, bookID, chapter}
+
+		// The server's read and write deadlines stay on the connection after
+		// the handshake takes it over, and would close a socket that is
+		// meant to stay open for as long as the page is.
+		rc := http.NewResponseController(w)
+		rc.SetReadDeadline(time.Time{})
+		rc.SetWriteDeadline(time.Time{})
+
+		server := websocket.Server{
+			Handshake: func(config *websocket.Config, r *http.Request) error {
+				return checkSocketOrigin(r, allowedOrigins)
+			},
+			Handler: func(ws *websocket.Conn) {
+				serveHighlightSocket(ws, key)
+			},
+		}
+		server.ServeHTTP(w, r)
+	}
+}
+
+// checkSocketOrigin accepts handshakes from the app's own pages, from
+// allowedOrigins, and from non-browser clients that send no Origin.
+func checkSocketOrigin(r *http.Request, allowedOrigins []string) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" || slices.Contains(allowedOrigins, origin) {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host != r.Host {
+		return fmt.Errorf("origin %q not allowed", origin)
+	}
+	return nil
+}
+
+// serveHighlightSocket writes events to ws until the client disconnects or
+// falls too far behind. Clients are not expected to send anything; reads only
+// serve to notice the connection closing.
+func serveHighlightSocket(ws *websocket.Conn, key chapterKey) {
+	c := hub.register(key)
+	defer hub.unregister(c)
+	defer ws.Close()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var discard string
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	for {
+		select {
+		case ev, ok := <-c.send:
+			if !ok {
+				return
+			}
+			if err := websocket.JSON.Send(ws, ev); err != nil {
+				slog.Warn("WebSocket send failed", "err", err)
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
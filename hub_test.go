@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestHighlightSocketReceivesEvents(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	mux.HandleFunc("/ws/highlights", requireUser(highlightsSocketHandler(nil)))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	ann := signUp(t, mux, "ann")
+
+	// Watch chapter 2, which the span below reaches into from chapter 1.
+	config, err := websocket.NewConfig("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/highlights?translation=KJV&bookId=1&chapter=2", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Header.Set("Cookie", ann.String())
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer ws.Close()
+
+	// The socket joins the hub after the handshake; wait until it has, so
+	// the event below is not published before anyone is listening.
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		hub.mu.Lock()
+		n := len(hub.clients)
+		hub.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("socket never registered with the hub")
+		}
+	}
+
+	span := strings.Replace(testHighlight("span"), `"verseId":"verse-1-1-1"`, `"verseId":"verse-1-1-31","endVerseId":"verse-1-2-3"`, 1)
+	other := strings.NewReplacer(`"verse-1-1-1"`, `"verse-1-3-1"`, `"chapter":1`, `"chapter":3`).Replace(testHighlight("other"))
+	for _, body := range []string{other, span} {
+		if rec := serve(mux, http.MethodPost, "/api/highlights", body, ann); rec.Code != http.StatusCreated {
+			t.Fatalf("create: status %d: %s", rec.Code, rec.Body)
+		}
+	}
+	if rec := serve(mux, http.MethodDelete, "/api/highlights/delete/span", "", ann); rec.Code != http.StatusOK {
+		t.Fatalf("delete: status %d: %s", rec.Code, rec.Body)
+	}
+
+	// The highlight in chapter 3 is not sent; the span's events are.
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	for _, want := range []string{"created", "deleted"} {
+		var ev HighlightEvent
+		if err := websocket.JSON.Receive(ws, &ev); err != nil {
+			t.Fatalf("waiting for %q: %v", want, err)
+		}
+		if ev.Type != want || ev.ID != "span" {
+			t.Errorf("event = %s %s, want %s span", ev.Type, ev.ID, want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// idempotencyKeyHeader lets a client that retries a create be sure it only
+// takes effect once: repeating a request with the same key returns the
+// response to the first one.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyKeyLifetime is how long a key is remembered. A retry after that
+// is treated as a new request.
+const idempotencyKeyLifetime = 24 * time.Hour
+
+// maxIdempotencyKeyLength bounds keys; clients generally send UUIDs.
+const maxIdempotencyKeyLength = 255
+
+// idempotencyKey returns the request's Idempotency-Key header, or "" if it
+// has none. The error is suitable to send back to the client.
+func idempotencyKey(r *http.Request) (string, error) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		return "", fmt.Errorf("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength)
+	}
+	return key, nil
+}
+
+// idempotentResponse returns the response body stored for the user's key by
+// an earlier request that has not yet expired. The boolean is false if there
+// is none. Run it in the transaction that would store the response, so two
+// requests with the same key cannot both miss.
+func idempotentResponse(ctx context.Context, tx *sql.Tx, userID int64, key string, now time.Time) ([]byte, bool, error) {
+	cutoff := now.Add(-idempotencyKeyLifetime).UTC().Format(time.RFC3339)
+	var body []byte
+	err := tx.QueryRowContext(ctx, `SELECT response FROM idempotency_keys WHERE userId = ? AND key = ? AND createdAt >= ?`,
+		userID, key, cutoff).Scan(&body)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return body, true, nil
+}
+
+// storeIdempotentResponse remembers body as the response to the user's key,
+// replacing an expired entry for it, and drops other expired keys.
+func storeIdempotentResponse(ctx context.Context, tx *sql.Tx, userID int64, key string, body []byte, now time.Time) error {
+	cutoff := now.Add(-idempotencyKeyLifetime).UTC().Format(time.RFC3339)
+	if _, err := tx.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE createdAt < ?`, cutoff); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `INSERT OR REPLACE INTO idempotency_keys (userId, key, response, createdAt) VALUES (?, ?, ?, ?)`,
+		userID, key, body, now.UTC().Format(time.RFC3339))
+	return err
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// exportHighlightsHandler streams every highlight of the current user as a
+// download, as a JSON array by default or as CSV with format=csv. Rows are
+// encoded as they are read so large collections are never held in memory at
+// once.
+func exportHighlightsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeJSONError(w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+
+	rows, err := db.QueryContext(r.Context(), `SELECT `+highlightColumns+` FROM highlights WHERE userId = ? AND deletedAt IS NULL
+	                       ORDER BY translation, bookId, chapter, verseId, start`, userIDFromContext(r.Context()))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	// Once the first byte is written the status is committed, so failures from
+	// here on can only be logged.
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="highlights.csv"`)
+		err = writeHighlightsCSV(w, rows)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="highlights.json"`)
+		err = writeHighlightsJSON(w, rows)
+	}
+	if err != nil {
+		slog.Error("Export failed", "err", err)
+	}
+}
+
+func writeHighlightsJSON(w io.Writer, rows *sql.Rows) error {
+	enc := json.NewEncoder(w)
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for first := true; rows.Next(); first = false {
+		h, err := scanHighlight(rows)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(h); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "]\n")
+	return err
+}
+
+// writeHighlightsCSV writes a header row and one row per highlight. The csv
+// writer takes care of quoting notes that contain commas, quotes or newlines.
+// Tags are joined with semicolons into a single column.
+func writeHighlightsCSV(w io.Writer, rows *sql.Rows) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "type", "verseId", "endVerseId", "start", "end", "note", "translation", "bookId", "chapter", "color", "strongsNumber", "needsReview", "tags", "createdAt", "updatedAt"}); err != nil {
+		return err
+	}
+	for rows.Next() {
+		h, err := scanHighlight(rows)
+		if err != nil {
+			return err
+		}
+		record := []string{
+			h.ID, h.Type, h.VerseID, h.EndVerseID, strconv.Itoa(h.Start), strconv.Itoa(h.End), h.Note,
+			h.Translation, strconv.Itoa(h.BookID), strconv.Itoa(h.Chapter), h.Color, h.StrongsNumber,
+			strconv.FormatBool(h.NeedsReview), strings.Join(h.Tags, ";"), h.CreatedAt, h.UpdatedAt,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// upsertHighlightSQL inserts a highlight or overwrites the existing row with
+// the same ID, used when restoring from an export. Importing a highlight that
+// is in the trash brings it back.
+const upsertHighlightSQL = insertHighlightSQL + `
+	          ON CONFLICT(id) DO UPDATE SET
+	              type = excluded.type, verseId = excluded.verseId, endVerseId = excluded.endVerseId, start = excluded.start, end = excluded.end,
+	              note = excluded.note, translation = excluded.translation, bookId = excluded.bookId,
+	              chapter = excluded.chapter, endChapter = excluded.endChapter, color = excluded.color,
+	              strongsNumber = excluded.strongsNumber, osisRef = excluded.osisRef, needsReview = excluded.needsReview, updatedAt = excluded.updatedAt,
+	              deletedAt = NULL
+	          WHERE highlights.userId = excluded.userId`
+
+// ImportSkip records a row of an import that was not stored, and why.
+type ImportSkip struct {
+	Kind   string `json:"kind,omitempty"` // Which list Index is into, for formats with several
+	Index  int    `json:"index"`
+	Reason string `json:"reason"`
+}
+
+// ImportResult summarizes an import run.
+type ImportResult struct {
+	Inserted int          `json:"inserted"`
+	Updated  int          `json:"updated"`
+	Skipped  []ImportSkip `json:"skipped"`
+}
+
+// importHighlightsHandler restores highlights from a file produced by
+// exportHighlightsHandler into the current user's account. Rows are upserted
+// by ID in one transaction; rows that are malformed, missing required fields
+// or whose ID belongs to another user are skipped and reported rather than
+// aborting the whole import.
+func importHighlightsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var raw []json.RawMessage
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+	err := json.NewDecoder(r.Body).Decode(&raw)
+	if isBodyTooLarge(err) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, bodyTooLargeMessage(maxImportBodyBytes))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body: expected a JSON array of highlights")
+		return
+	}
+
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to begin transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	stmt, err := tx.PrepareContext(r.Context(), upsertHighlightSQL)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to prepare statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer stmt.Close()
+
+	result := ImportResult{Skipped: []ImportSkip{}}
+	var stored []Highlight
+	for i, msg := range raw {
+		var h Highlight
+		if err := json.Unmarshal(msg, &h); err != nil {
+			result.Skipped = append(result.Skipped, ImportSkip{Index: i, Reason: "invalid JSON object"})
+			continue
+		}
+		if err := h.validate(); err != nil {
+			result.Skipped = append(result.Skipped, ImportSkip{Index: i, Reason: err.Error()})
+			continue
+		}
+		var ok bool
+		if h.StrongsNumber, ok = normalizeStrongsNumber(h.StrongsNumber); !ok {
+			result.Skipped = append(result.Skipped, ImportSkip{Index: i, Reason: invalidStrongsNumberMessage})
+			continue
+		}
+
+		h.UserID = userIDFromContext(r.Context())
+		stampHighlight(&h)
+
+		exists, skip, err := importHighlight(r.Context(), tx, stmt, &h)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to import highlight at index %d", i))
+			slog.Error("Database error", "err", err)
+			return
+		}
+		if skip != "" {
+			result.Skipped = append(result.Skipped, ImportSkip{Index: i, Reason: skip})
+			continue
+		}
+		stored = append(stored, h)
+		if exists {
+			result.Updated++
+		} else {
+			result.Inserted++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to commit transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	for _, h := range stored {
+		hub.publishHighlight("updated", h)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// importHighlight upserts h within tx through stmt, prepared from
+// upsertHighlightSQL, and reports whether it replaced an existing highlight.
+// The user's palette is applied to h first, as when creating one. If h's ID
+// belongs to another user or its color does not match the palette, nothing
+// is written and skip gives the reason.
+func importHighlight(ctx context.Context, tx *sql.Tx, stmt *sql.Stmt, h *Highlight) (exists bool, skip string, err error) {
+	var owner int64
+	err = tx.QueryRowContext(ctx, `SELECT userId FROM highlights WHERE id = ?`, h.ID).Scan(&owner)
+	if err != nil && err != sql.ErrNoRows {
+		return false, "", err
+	}
+	exists = err == nil
+	if exists && owner != h.UserID {
+		return false, "id is already in use", nil
+	}
+	if msg, err := applyPalette(ctx, tx, h); err != nil || msg != "" {
+		return false, msg, err
+	}
+	if _, err := stmt.ExecContext(ctx, insertHighlightArgs(*h)...); err != nil {
+		return false, "", err
+	}
+	// Rows exported before tags existed have no tags field; leave any tags
+	// already on the highlight alone in that case.
+	if h.Tags != nil {
+		if err := setHighlightTags(ctx, tx, *h); err != nil {
+			return false, "", err
+		}
+	}
+	return exists, "", nil
+}
+
+// markdownHighlightsHandler renders the notes the user wrote in one chapter
+// as Markdown, under a heading for each verse that has any, in verse order.
+// Highlights without a note are left out. A span starting in an earlier
+// chapter is included, as in the GET, under the verse it starts on.
+func markdownHighlightsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	translation, bookID, chapter, err := parseChapterQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := `SELECT chapter, ` + verseNumberSQL + `, note FROM highlights
+	          WHERE userId = ? AND translation = ? AND bookId = ? AND chapter <= ? AND endChapter >= ?
+	              AND deletedAt IS NULL AND note IS NOT NULL AND note != ''
+	          ORDER BY chapter, ` + verseNumberSQL + `, start, rowid`
+	rows, err := db.QueryContext(r.Context(), query, userIDFromContext(r.Context()), translation, bookID, chapter, chapter)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	var b strings.Builder
+	lastChapter, lastVerse := 0, 0
+	for rows.Next() {
+		var startChapter, verse int
+		var note string
+		if err := rows.Scan(&startChapter, &verse, &note); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		if startChapter != lastChapter || verse != lastVerse {
+			if b.Len() > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "## %s %d:%d\n\n", bookName(bookID), startChapter, verse)
+			lastChapter, lastVerse = startChapter, verse
+		} else {
+			b.WriteString("\n")
+		}
+		b.WriteString(strings.TrimSpace(note) + "\n")
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	io.WriteString(w, b.String())
+}
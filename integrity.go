@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// InvalidHighlight is a highlight whose reference names a verse that does
+// not exist, as reported by validateHighlightsHandler.
+type InvalidHighlight struct {
+	Highlight Highlight `json:"highlight"`
+	Problem   string    `json:"problem"`
+	Action    string    `json:"action,omitempty"` // "clamped" or "trashed", when fixing
+}
+
+// lastVerseColumns select the number of verses in the chapters a highlight
+// starts and ends in, as stored for its translation, or 0 if those chapters
+// have not been fetched. Like tagsColumn they are correlated with an outer
+// query selecting FROM highlights.
+const lastVerseColumns = `COALESCE((SELECT MAX(v.verse) FROM verses v
+	              WHERE v.translation = highlights.translation AND v.bookId = highlights.bookId AND v.chapter = highlights.chapter), 0),
+	          COALESCE((SELECT MAX(v.verse) FROM verses v
+	              WHERE v.translation = highlights.translation AND v.bookId = highlights.bookId AND v.chapter = highlights.endChapter), 0)`
+
+// metadataVerseCount returns how many verses a chapter has in the bundled
+// metadata, or 0 if the book or chapter does not exist.
+func metadataVerseCount(bookID, chapter int) int {
+	if bookID < 1 || bookID > len(metadata.Books) {
+		return 0
+	}
+	verses := metadata.Books[bookID-1].Verses
+	if chapter < 1 || chapter > len(verses) {
+		return 0
+	}
+	return verses[chapter-1]
+}
+
+// checkReference reports what is wrong with the verses h refers to, or ""
+// if they all exist. Verse counts come from the translation's own text when
+// it has been fetched, passed in as lastVerse and endLastVerse, and otherwise
+// from the bundled metadata, whose versification is the KJV's.
+//
+// It also returns h with out-of-range verses clamped to the nearest one that
+// exists, and a span that cannot be repaired reduced to its first verse. The
+// boolean is false if h cannot be repaired at all because its book, chapter
+// or verseId is unusable.
+func checkReference(h Highlight, lastVerse, endLastVerse int) (problem string, fixed Highlight, ok bool) {
+	if h.BookID < 1 || h.BookID > len(metadata.Books) {
+		return fmt.Sprintf("bookId %d does not exist", h.BookID), h, false
+	}
+	book := metadata.Books[h.BookID-1]
+	if h.Chapter < 1 || h.Chapter > book.Chapters {
+		return fmt.Sprintf("%s has no chapter %d", book.Name, h.Chapter), h, false
+	}
+	bookID, chapter, verse, parsed := parseVerseID(h.VerseID)
+	if !parsed || bookID != h.BookID || chapter != h.Chapter {
+		return fmt.Sprintf("verseId %q is not a verse of %s %d", h.VerseID, book.Name, h.Chapter), h, false
+	}
+
+	var problems []string
+	if lastVerse == 0 {
+		lastVerse = metadataVerseCount(h.BookID, h.Chapter)
+	}
+	if verse < 1 || verse > lastVerse {
+		problems = append(problems, fmt.Sprintf("%s %d:%d does not exist; the chapter has %d verses", book.Name, h.Chapter, verse, lastVerse))
+		verse = min(max(verse, 1), lastVerse)
+		h.VerseID = verseID(h.BookID, h.Chapter, verse)
+	}
+
+	if h.EndVerseID != "" {
+		endBook, endChapter, endVerse, parsed := parseVerseID(h.EndVerseID)
+		switch {
+		case !parsed || endBook != h.BookID:
+			problems = append(problems, fmt.Sprintf("endVerseId %q is not a verse of %s", h.EndVerseID, book.Name))
+			h.EndVerseID = ""
+		case endChapter > book.Chapters:
+			problems = append(problems, fmt.Sprintf("%s has no chapter %d", book.Name, endChapter))
+			endChapter = book.Chapters
+			h.EndVerseID = verseID(h.BookID, endChapter, metadataVerseCount(h.BookID, endChapter))
+		default:
+			if endLastVerse == 0 {
+				endLastVerse = metadataVerseCount(h.BookID, endChapter)
+			}
+			if endVerse < 1 || endVerse > endLastVerse {
+				problems = append(problems, fmt.Sprintf("%s %d:%d does not exist; the chapter has %d verses", book.Name, endChapter, endVerse, endLastVerse))
+				h.EndVerseID = verseID(h.BookID, endChapter, min(max(endVerse, 1), endLastVerse))
+			}
+		}
+		if h.EndVerseID != "" && spanError(h) != "" {
+			h.EndVerseID = ""
+		}
+	}
+
+	return strings.Join(problems, "; "), h, true
+}
+
+// validateHighlightsHandler lists the current user's highlights that refer to
+// verses that do not exist. POST with fix=clamp repairs them as well: verses
+// out of range are clamped to the nearest one that exists and highlights
+// beyond repair are moved to the trash, where they can still be restored.
+func validateHighlightsHandler(w http.ResponseWriter, r *http.Request) {
+	fix := r.URL.Query().Get("fix")
+	switch {
+	case fix != "" && fix != "clamp":
+		writeJSONError(w, http.StatusBadRequest, `fix must be "clamp" when given`)
+		return
+	case fix == "" && r.Method != http.MethodGet, fix != "" && r.Method != http.MethodPost:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed; use GET to check and POST with fix=clamp to repair")
+		return
+	}
+
+	userID := userIDFromContext(r.Context())
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to begin transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	query := `SELECT ` + highlightColumns + `, ` + lastVerseColumns + ` FROM highlights
+	          WHERE userId = ? AND deletedAt IS NULL ORDER BY bookId, chapter, rowid`
+	rows, err := tx.QueryContext(r.Context(), query, userID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	invalid := []InvalidHighlight{}
+	var repairs []Highlight // Clamped copies of invalid; the zero Highlight for ones to trash
+	for rows.Next() {
+		var lastVerse, endLastVerse int
+		h, err := scanHighlight(extraColumns{rows, []any{&lastVerse, &endLastVerse}})
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		h.UserID = userID
+		problem, fixed, ok := checkReference(h, lastVerse, endLastVerse)
+		if problem == "" {
+			continue
+		}
+		invalid = append(invalid, InvalidHighlight{Highlight: h, Problem: problem})
+		if !ok {
+			fixed = Highlight{}
+		}
+		repairs = append(repairs, fixed)
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	rows.Close()
+
+	if fix == "" || len(invalid) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(invalid)
+		return
+	}
+
+	now := timestamp()
+	for i := range invalid {
+		h := &invalid[i].Highlight
+		if repairs[i].ID == "" {
+			_, err = tx.StmtContext(r.Context(), trashHighlightStmt).ExecContext(r.Context(), now, h.ID, userID)
+			invalid[i].Action = "trashed"
+			h.DeletedAt = now
+		} else {
+			fixed := repairs[i]
+			fixed.UpdatedAt = now
+			endChapter := fixed.Chapter
+			if _, c, _, ok := parseVerseID(fixed.EndVerseID); ok {
+				endChapter = c
+			}
+			fixed.OSISRef = highlightOSISRef(fixed)
+			_, err = tx.ExecContext(r.Context(), `UPDATE highlights SET verseId = ?, endVerseId = ?, endChapter = ?, osisRef = ?, updatedAt = ?
+			                                      WHERE id = ? AND userId = ?`,
+				fixed.VerseID, fixed.EndVerseID, endChapter, fixed.OSISRef, now, fixed.ID, userID)
+			invalid[i].Action = "clamped"
+			repairs[i] = fixed
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+			slog.Error("Database error", "err", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to commit transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	for i, inv := range invalid {
+		if inv.Action == "trashed" {
+			hub.publishDeleted(inv.Highlight)
+		} else {
+			hub.publishHighlight("updated", repairs[i])
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invalid)
+}
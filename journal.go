@@ -0,0 +1,118 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// JournalEntry is one note in the study journal, with the reference and, when
+// the chapter has been loaded before, the text of the verse it is on.
+type JournalEntry struct {
+	ID          string `json:"id"`
+	Reference   string `json:"reference"`
+	Translation string `json:"translation"`
+	BookID      int    `json:"bookId"`
+	Chapter     int    `json:"chapter"`
+	Verse       int    `json:"verse"`
+	VerseText   string `json:"verseText,omitempty"`
+	Note        string `json:"note"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+// parseDateBound parses a from or to query parameter given either as an
+// RFC3339 time or as a date. A date used as an upper bound includes the whole
+// day, so it is returned as the start of the next day for an exclusive
+// comparison. The result is in the form createdAt is stored in.
+func parseDateBound(name, value string, upper bool) (string, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Format(time.RFC3339), nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return "", fmt.Errorf("%s must be a date (YYYY-MM-DD) or an RFC3339 time", name)
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t.Format(time.RFC3339), nil
+}
+
+// journalHandler returns every note the user has written, in canonical verse
+// order, optionally limited to one translation and to notes created within
+// from and to. Highlights without a note are left out.
+func journalHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	where := `userId = ? AND deletedAt IS NULL AND note IS NOT NULL AND note != ''`
+	args := []any{userIDFromContext(r.Context())}
+	if translation := r.URL.Query().Get("translation"); translation != "" {
+		where += ` AND translation = ?`
+		args = append(args, translation)
+	}
+	if s := r.URL.Query().Get("from"); s != "" {
+		from, err := parseDateBound("from", s, false)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		where += ` AND createdAt >= ?`
+		args = append(args, from)
+	}
+	if s := r.URL.Query().Get("to"); s != "" {
+		to, err := parseDateBound("to", s, true)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		where += ` AND createdAt < ?`
+		args = append(args, to)
+	}
+
+	// The verse number is worked out in a subquery so that its unqualified
+	// columns refer to highlights rather than the joined verses table.
+	query := `SELECT h.id, h.translation, h.bookId, h.chapter, h.verse, v.text, h.note, h.createdAt
+	          FROM (SELECT id, translation, bookId, chapter, ` + verseNumberSQL + ` AS verse, note, createdAt, start, rowid AS seq
+	                FROM highlights WHERE ` + where + `) h
+	          LEFT JOIN verses v ON v.translation = h.translation AND v.bookId = h.bookId
+	              AND v.chapter = h.chapter AND v.verse = h.verse
+	          ORDER BY h.bookId, h.chapter, h.verse, h.start, h.seq`
+
+	rows, err := db.QueryContext(r.Context(), query, args...)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	entries := []JournalEntry{}
+	for rows.Next() {
+		var e JournalEntry
+		var text sql.NullString
+		if err := rows.Scan(&e.ID, &e.Translation, &e.BookID, &e.Chapter, &e.Verse, &text, &e.Note, &e.CreatedAt); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		e.Reference = fmt.Sprintf("%s %d:%d", bookName(e.BookID), e.Chapter, e.Verse)
+		if text.Valid {
+			e.VerseText = verseText(text.String)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
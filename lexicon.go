@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// lexiconEntry is one entry of the Open Scriptures Strong's dictionaries
+// (https://github.com/openscriptures/strongs), combined into a single JSON
+// object keyed by Strong's number, e.g. "G26" or "H430".
+type lexiconEntry struct {
+	Lemma      string `json:"lemma"`
+	Translit   string `json:"translit"`
+	StrongsDef string `json:"strongs_def"`
+	KJVDef     string `json:"kjv_def"`
+}
+
+// lexiconProvider answers lookups from a bundled Strong's lexicon when Blue
+// Letter Bible is unavailable. Without an interlinear it cannot know which
+// word of the verse was clicked, so it matches the English word against each
+// entry's KJV renderings and picks the lowest-numbered match in the right
+// testament. That is a best-effort fallback, not a precise lookup.
+type lexiconProvider struct {
+	entries map[string]lexiconEntry
+	// byWord maps a normalized English rendering to the Strong's numbers
+	// that use it, in numeric order.
+	byWord map[string][]string
+}
+
+// offlineLexicon is the loaded lexicon, or nil if there is none.
+var offlineLexicon *lexiconProvider
+
+// loadLexicon reads a lexicon file. It returns nil without an error for an
+// empty path, leaving the fallback out, but a missing file is an error so the
+// fallback is not lost unnoticed; scripts/fetch_data.go downloads it.
+func loadLexicon(path string) (*lexiconProvider, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w; run `go run scripts/fetch_data.go lexicon` to download it, or pass -lexicon= to rely on Blue Letter Bible alone", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p := &lexiconProvider{byWord: make(map[string][]string)}
+	if err := json.Unmarshal(data, &p.entries); err != nil {
+		return nil, err
+	}
+
+	for number, entry := range p.entries {
+		for _, rendering := range strings.FieldsFunc(entry.KJVDef, func(r rune) bool { return !unicode.IsLetter(r) }) {
+			key := normalizeWord(rendering)
+			if !slices.Contains(p.byWord[key], number) {
+				p.byWord[key] = append(p.byWord[key], number)
+			}
+		}
+	}
+	for _, numbers := range p.byWord {
+		slices.SortFunc(numbers, compareStrongsNumbers)
+	}
+	return p, nil
+}
+
+// compareStrongsNumbers orders Strong's numbers by prefix, then numerically.
+func compareStrongsNumbers(a, b string) int {
+	if a[0] != b[0] {
+		return int(a[0]) - int(b[0])
+	}
+	x, _ := strconv.Atoi(a[1:])
+	y, _ := strconv.Atoi(b[1:])
+	return x - y
+}
+
+func (p *lexiconProvider) Lookup(ctx context.Context, word string, ref VerseRef) (StrongsDefinition, error) {
+	// Hebrew numbers for the Old Testament, Greek for the New. An unknown
+	// book name leaves both in play.
+	prefix := ""
+	if bookID, ok := bookIDByName(ref.BookName); ok {
+		prefix = "G"
+		if bookID <= lastOldTestamentBook {
+			prefix = "H"
+		}
+	}
+
+	for _, number := range p.byWord[normalizeWord(word)] {
+		if !strings.HasPrefix(number, prefix) {
+			continue
+		}
+		return p.entries[number].definition(number), nil
+	}
+
+	return StrongsDefinition{}, &lookupError{http.StatusNotFound, "Word not found in the offline Strong's lexicon"}
+}
+
+// LookupNumber returns the lexicon's entry for number.
+func (p *lexiconProvider) LookupNumber(ctx context.Context, number string) (StrongsDefinition, error) {
+	entry, ok := p.entries[number]
+	if !ok {
+		return StrongsDefinition{}, &lookupError{http.StatusNotFound, "Strong's number not found in the offline lexicon"}
+	}
+	return entry.definition(number), nil
+}
+
+// definition renders e, the entry for number, as a StrongsDefinition, with
+// the KJV renderings after the definition proper.
+func (e lexiconEntry) definition(number string) StrongsDefinition {
+	definition := strings.TrimSpace(e.StrongsDef)
+	if e.KJVDef != "" {
+		definition += "\n\nKJV: " + strings.TrimSpace(e.KJVDef)
+	}
+	return StrongsDefinition{
+		StrongsNumber:   number,
+		Lexeme:          e.Lemma,
+		Transliteration: e.Translit,
+		Definition:      definition,
+		DefinitionHTML:  textDefinitionHTML(definition),
+	}
+}
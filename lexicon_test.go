@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLexiconLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "strongs.json")
+	data := `{
+		"G26": {"lemma": "ἀγάπη", "translit": "agápē", "strongs_def": "love", "kjv_def": "(feast of) charity(-ably), dear, love"},
+		"H157": {"lemma": "אָהַב", "translit": "ʼâhab", "strongs_def": "to have affection for", "kjv_def": "(be-)love(-d, -ly, -r), like, friend"},
+		"G5384": {"lemma": "φίλος", "translit": "phílos", "strongs_def": "a friend", "kjv_def": "friend"}
+	}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lexicon, err := loadLexicon(path)
+	if err != nil {
+		t.Fatalf("loadLexicon: %v", err)
+	}
+
+	tests := []struct {
+		word, book string
+		want       string // Strong's number, or "" for not found
+	}{
+		{"love", "John", "G26"},
+		{"love", "Genesis", "H157"},
+		{"Friend", "Genesis", "H157"},
+		{"friend", "John", "G5384"},
+		{"charity", "1 Corinthians", "G26"},
+		{"beginning", "John", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.word+" in "+tt.book, func(t *testing.T) {
+			def, err := lexicon.Lookup(context.Background(), tt.word, VerseRef{Translation: "KJV", BookName: tt.book, Chapter: "1", Verse: "1"})
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("found %s, want not found", def.StrongsNumber)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Lookup: %v", err)
+			}
+			if def.StrongsNumber != tt.want {
+				t.Errorf("got %s, want %s", def.StrongsNumber, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadLexiconMissingFile(t *testing.T) {
+	if _, err := loadLexicon(filepath.Join(t.TempDir(), "gone.json")); err == nil {
+		t.Error("missing file was not an error")
+	}
+	if lexicon, err := loadLexicon(""); lexicon != nil || err != nil {
+		t.Errorf("empty path: got %v, %v; want nil, nil", lexicon, err)
+	}
+}
@@ -1,130 +1,848 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"html/template"
-	"log"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
-	"github.com/PuerkitoBio/goquery"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
+// shutdownTimeout bounds how long in-flight requests get to finish on exit.
+const shutdownTimeout = 10 * time.Second
+
+// defaultWriteTimeout is the default -write-timeout. A Strong's lookup may
+// fetch two Blue Letter Bible pages of fetchAttempts tries of up to
+// fetchTimeout each, a little over a minute in the worst case, so it leaves
+// room for that rather than cutting the response off.
+const defaultWriteTimeout = 2 * time.Minute
+
+// templatesDir holds the *.html templates parsed into tmpl, set by
+// -templates.
+var templatesDir = "templates"
+
 var tmpl *template.Template
 var db *sql.DB
 
+// reloadTemplates makes templates re-parse on every request, set by -dev so
+// template edits show up without a restart.
+var reloadTemplates bool
+
+// templates returns the parsed page templates: the copy parsed at startup, or
+// a fresh parse in development mode.
+func templates() (*template.Template, error) {
+	if reloadTemplates {
+		return parseTemplates()
+	}
+	return tmpl, nil
+}
+
+// parseTemplates parses every *.html file in templatesDir. Unlike
+// template.ParseGlob, it names the directory when there is nothing to parse.
+func parseTemplates() (*template.Template, error) {
+	files, err := filepath.Glob(filepath.Join(templatesDir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no templates found in %s", templatesDir)
+	}
+	return template.ParseFiles(files...)
+}
+
 // Highlight represents a user-saved highlight or note in the database.
 type Highlight struct {
-	ID          string `json:"id"`
-	Type        string `json:"type"`
-	VerseID     string `json:"verseId"`
-	Start       int    `json:"start"`
-	End         int    `json:"end"`
-	Note        string `json:"note,omitempty"`
-	Translation string `json:"translation"`
-	BookID      int    `json:"bookId"`
-	Chapter     int    `json:"chapter"`
+	ID            string   `json:"id"`
+	Type          string   `json:"type"`
+	VerseID       string   `json:"verseId"`
+	EndVerseID    string   `json:"endVerseId,omitempty"`
+	Start         int      `json:"start"`
+	End           int      `json:"end"`
+	Note          string   `json:"note,omitempty"`
+	Translation   string   `json:"translation"`
+	BookID        int      `json:"bookId"`
+	Chapter       int      `json:"chapter"`
+	Color         string   `json:"color"`
+	StrongsNumber string   `json:"strongsNumber,omitempty"`
+	OSISRef       string   `json:"osisRef,omitempty"` // The first verse, named the same way in every translation
+	NeedsReview   bool     `json:"needsReview,omitempty"`
+	Tags          []string `json:"tags"`
+	CreatedAt     string   `json:"createdAt"`
+	UpdatedAt     string   `json:"updatedAt"`
+	DeletedAt     string   `json:"deletedAt,omitempty"` // Set while the highlight is in the trash
+	VerseText     string   `json:"verseText,omitempty"` // Only filled in when asked for with includeText
+	UserID        int64    `json:"-"`
+}
+
+// highlightColumns is the column list scanned by scanHighlight.
+const highlightColumns = `id, type, verseId, endVerseId, start, end, note, translation, bookId, chapter, color, strongsNumber, osisRef, needsReview, createdAt, updatedAt, COALESCE(deletedAt, ''), ` + tagsColumn
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// extraColumns scans rows that select more columns after highlightColumns,
+// so they can still be read with scanHighlight. The extra columns go to dest.
+type extraColumns struct {
+	rowScanner
+	dest []any
+}
+
+func (s extraColumns) Scan(dest ...any) error {
+	return s.rowScanner.Scan(append(dest, s.dest...)...)
+}
+
+// scanHighlight reads a row selected with highlightColumns into a Highlight.
+func scanHighlight(s rowScanner) (Highlight, error) {
+	var h Highlight
+	var note sql.NullString // Handle possible NULL values for note
+	var tags sql.NullString
+	if err := s.Scan(&h.ID, &h.Type, &h.VerseID, &h.EndVerseID, &h.Start, &h.End, &note, &h.Translation, &h.BookID, &h.Chapter, &h.Color, &h.StrongsNumber, &h.OSISRef, &h.NeedsReview, &h.CreatedAt, &h.UpdatedAt, &h.DeletedAt, &tags); err != nil {
+		return h, err
+	}
+	h.Tags = splitTags(tags)
+	if note.Valid {
+		h.Note = note.String
+	}
+	return h, nil
+}
+
+// envOr returns the value of the environment variable key, or fallback when
+// it is unset or empty.
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// dataFile returns path, the value of the flag name, or "" when the flag was
+// left at its default and the file there has not been downloaded, so a fresh
+// checkout still starts without the optional data. A path passed explicitly
+// is returned as is, and loading it fails if it is missing.
+func dataFile(name, path string) string {
+	explicit := false
+	flag.Visit(func(f *flag.Flag) { explicit = explicit || f.Name == name })
+	if explicit {
+		return path
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("Optional data file not found; running without it", "path", path, "download", "go run scripts/fetch_data.go "+name)
+		return ""
+	}
+	return path
+}
+
+// fatal logs msg at Error level with the given attributes and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
 
 func main() {
+	dbPath := flag.String("db", envOr("BIBLE_DB_PATH", "./bible_app.db"), "path to the SQLite database file (env BIBLE_DB_PATH)")
+	interlinearPath := flag.String("interlinear", "data/interlinear.tsv", "original-language interlinear file loaded into an empty database; empty, or the default when it has not been downloaded, runs without original-language text (see data/README.md)")
+	proxy := flag.String("proxy", "", "proxy URL for outbound requests; when empty HTTP_PROXY and HTTPS_PROXY are used")
+	lexiconCacheDir := flag.String("lexicon-cache-dir", "", "directory to keep fetched Blue Letter Bible pages in; empty disables the disk cache")
+	lexiconCacheMaxAge := flag.Duration("lexicon-cache-max-age", 30*24*time.Hour, "how long a page in -lexicon-cache-dir is used before it is fetched again")
+	lexiconPath := flag.String("lexicon", "data/strongs.json", "offline Strong's lexicon used when Blue Letter Bible lookups fail; empty, or the default when it has not been downloaded, runs without it (see data/README.md)")
+	crossRefsPath := flag.String("crossrefs", "data/cross_references.txt", "OpenBible.info cross-reference file loaded into an empty database; empty, or the default when it has not been downloaded, runs without cross references (see data/README.md)")
+	adminToken := flag.String("admin-token", envOr("BIBLE_ADMIN_TOKEN", ""), "shared secret required in the X-Admin-Token header by admin endpoints; empty disables them (env BIBLE_ADMIN_TOKEN)")
+	addr := flag.String("addr", ":8080", "address to listen on, such as 127.0.0.1:9000")
+	readHeaderTimeout := flag.Duration("read-header-timeout", 10*time.Second, "how long a client has to send the request headers")
+	readTimeout := flag.Duration("read-timeout", time.Minute, "how long a client has to send the whole request, including its body")
+	writeTimeout := flag.Duration("write-timeout", defaultWriteTimeout, "how long a handler has to finish writing its response, counted from the end of the request headers")
+	idleTimeout := flag.Duration("idle-timeout", 2*time.Minute, "how long an idle keep-alive connection is kept open waiting for the next request")
+	logLevel := flag.String("loglevel", envOr("BIBLE_LOG_LEVEL", "info"), "minimum level logged: debug, info, warn or error (env BIBLE_LOG_LEVEL)")
+	staticMaxAge := flag.Duration("static-max-age", time.Hour, "how long browsers may cache files under /static/ before checking for changes; 0 disables caching")
+	csp := flag.String("csp", defaultContentSecurityPolicy, "Content-Security-Policy header sent with every response; empty sends none")
+	corsOrigins := flag.String("cors-origins", envOr("BIBLE_CORS_ORIGINS", ""), "comma-separated origins allowed to call the API from a browser (env BIBLE_CORS_ORIGINS)")
+	audioBaseURL := flag.String("audio-base-url", envOr("BIBLE_AUDIO_BASE_URL", ""), "base URL of the audio Bible provider serving {code}/{OSIS book}/{chapter}.mp3; empty disables /api/audio (env BIBLE_AUDIO_BASE_URL)")
+	audioTranslations := flag.String("audio-translations", "KJV", "comma-separated translations with audio at -audio-base-url, each optionally followed by =code naming the provider's directory for it")
+	flag.StringVar(&templatesDir, "templates", templatesDir, "directory containing the HTML page templates")
+	flag.BoolVar(&reloadTemplates, "dev", false, "re-parse templates on every request instead of once at startup")
+	flag.Parse()
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -loglevel %q: use debug, info, warn or error\n", *logLevel)
+		os.Exit(2)
+	}
+	// Route the standard logger through slog too, so messages from the
+	// http package and dependencies end up in the same JSON stream.
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
 	var err error
-	db, err = sql.Open("sqlite3", "./bible_app.db")
+	if db, err = openDB(*dbPath); err != nil {
+		fatal("Error opening database", "err", err)
+	}
+
+	if err := loadCrossReferences(dataFile("crossrefs", *crossRefsPath)); err != nil {
+		fatal("Error loading cross references", "err", err)
+	}
+
+	if err := loadInterlinear(dataFile("interlinear", *interlinearPath)); err != nil {
+		fatal("Error loading interlinear data", "err", err)
+	}
+
+	if *proxy != "" {
+		f, err := proxyFetcher(*proxy)
+		if err != nil {
+			fatal("Invalid -proxy", "err", err)
+		}
+		fetcher = f
+	}
+
+	if *lexiconCacheDir != "" {
+		if err := os.MkdirAll(*lexiconCacheDir, 0o755); err != nil {
+			fatal("Error creating lexicon cache directory", "err", err)
+		}
+		lexiconCache = &pageCache{dir: *lexiconCacheDir, maxAge: *lexiconCacheMaxAge}
+	}
+
+	if *audioBaseURL != "" {
+		if audio, err = newAudioSource(*audioBaseURL, *audioTranslations); err != nil {
+			fatal("Invalid audio configuration", "err", err)
+		}
+	}
+
+	lexicon, err := loadLexicon(dataFile("lexicon", *lexiconPath))
 	if err != nil {
-		log.Fatal(err)
+		fatal("Error loading lexicon", "err", err)
+	}
+	if lexicon != nil {
+		offlineLexicon = lexicon
+		strongsProviders = append(strongsProviders, lexicon)
+	} else {
+		slog.Warn("No lexicon configured; Strong's lookups will rely on Blue Letter Bible only")
 	}
-	defer db.Close()
 
-	createTableSQL := `CREATE TABLE IF NOT EXISTS highlights (
-		"id" TEXT NOT NULL PRIMARY KEY,
-		"type" TEXT NOT NULL,
-		"verseId" TEXT NOT NULL,
-		"start" INTEGER NOT NULL,
-		"end" INTEGER NOT NULL,
-		"note" TEXT,
-		"translation" TEXT NOT NULL,
-		"bookId" INTEGER NOT NULL,
-		"chapter" INTEGER NOT NULL
-	);`
+	go purgeTrashPeriodically()
 
-	_, err = db.Exec(createTableSQL)
+	if tmpl, err = parseTemplates(); err != nil {
+		fatal("Error parsing templates", "err", err)
+	}
+
+	allowedOrigins := parseOrigins(*corsOrigins)
+	mux := newMux(*adminToken, *staticMaxAge, allowedOrigins)
+
+	// Start server
+	cors := corsMiddleware(allowedOrigins)
+	api := func(next http.Handler) http.Handler { return cors(gzipMiddleware(next)) }
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           loggingMiddleware(securityHeaders(*csp, forAPI(api, metricsMiddleware(mux)))),
+		ReadHeaderTimeout: *readHeaderTimeout,
+		ReadTimeout:       *readTimeout,
+		WriteTimeout:      *writeTimeout,
+		IdleTimeout:       *idleTimeout,
+	}
+	// Listen before serving so the message shows the resolved address, such
+	// as the port picked for ":0".
+	ln, err := net.Listen("tcp", srv.Addr)
 	if err != nil {
-		log.Fatalf("Error creating table: %q", err)
+		fatal("Error listening", "err", err)
 	}
+	go func() {
+		slog.Info("Server starting", "addr", ln.Addr().String())
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			fatal("Server failed", "err", err)
+		}
+	}()
+
+	// Wait for SIGINT/SIGTERM, then let in-flight requests finish before
+	// closing the database so writes are not cut off mid-transaction.
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+
+	slog.Info("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("Server shutdown did not complete", "err", err)
+	}
+	if err := closeStatements(); err != nil {
+		slog.Error("Error closing statements", "err", err)
+	}
+	if err := db.Close(); err != nil {
+		slog.Error("Error closing database", "err", err)
+	}
+}
+
+// newMux registers every route. Routes are registered on an explicit mux so
+// unknown /api/ paths can be told apart from the index page.
+func newMux(adminToken string, staticMaxAge time.Duration, allowedOrigins []string) *http.ServeMux {
+	mux := http.NewServeMux()
+
 	// Serve static files from the "static" directory
 	fs := http.FileServer(http.Dir("static"))
-	http.Handle("/static/", http.StripPrefix("/static/", fs))
-
-	// Parse templates
-	tmpl = template.Must(template.ParseGlob("templates/*.html"))
+	mux.Handle("/static/", cacheStatic(staticMaxAge, http.StripPrefix("/static/", fs)))
 
 	// Handlers
-	http.HandleFunc("/", indexHandler)
-	http.HandleFunc("/api/highlights", highlightsHandler)
-	http.HandleFunc("/api/highlights/delete/", deleteHighlightHandler)
-	http.HandleFunc("/api/strongs_definition", strongsDefinitionHandler)
+	mux.HandleFunc("/", indexHandler)
+	mux.HandleFunc("/healthz", healthHandler)
+	mux.HandleFunc("/metrics", metricsHandler)
+	mux.HandleFunc("/print", requireUser(printHandler))
+	mux.HandleFunc("/share/", sharedPageHandler)
+	mux.HandleFunc("/api/register", registerHandler)
+	mux.HandleFunc("/api/login", loginHandler)
+	mux.HandleFunc("/api/logout", logoutHandler)
+	mux.HandleFunc("/api/me", requireUser(currentUserHandler))
+	mux.HandleFunc("/api/highlights", requireUser(highlightsHandler))
+	mux.HandleFunc("/api/highlights/", requireUser(highlightHandler))
+	mux.HandleFunc("/api/highlights/bulk", requireUser(createHighlightsBulkHandler))
+	mux.HandleFunc("/api/highlights/search", requireUser(searchHighlightsHandler))
+	mux.HandleFunc("/api/highlights/export", requireUser(exportHighlightsHandler))
+	mux.HandleFunc("/api/highlights/import", requireUser(importHighlightsHandler))
+	mux.HandleFunc("/api/import/youversion", requireUser(importYouVersionHandler))
+	mux.HandleFunc("/api/highlights/by_tag", requireUser(highlightsByTagHandler))
+	mux.HandleFunc("/api/highlights/recent", requireUser(recentHighlightsHandler))
+	mux.HandleFunc("/api/highlights/verses", requireUser(highlightedVersesHandler))
+	mux.HandleFunc("/api/highlights/markdown", requireUser(markdownHighlightsHandler))
+	mux.HandleFunc("/api/highlights/copy", requireUser(copyHighlightsHandler))
+	mux.HandleFunc("/api/highlights/compare", requireUser(compareHighlightsHandler))
+	mux.HandleFunc("/api/highlights/validate", requireUser(validateHighlightsHandler))
+	mux.HandleFunc("/api/highlights/overlaps", requireUser(overlapsHandler))
+	mux.HandleFunc("/api/highlights/delete/", requireUser(deleteHighlightHandler))
+	mux.HandleFunc("/api/highlights/trash", requireUser(trashHandler))
+	mux.HandleFunc("/api/highlights/changes", requireUser(highlightChangesHandler))
+	mux.HandleFunc("/api/progress", requireUser(progressHandler))
+	mux.HandleFunc("/api/palette", requireUser(paletteHandler))
+	mux.HandleFunc("/api/share", requireUser(createShareHandler))
+	mux.HandleFunc("/api/bookmarks", requireUser(bookmarksHandler))
+	mux.HandleFunc("/api/bookmarks/", requireUser(bookmarkHandler))
+	mux.HandleFunc("/api/collections", requireUser(collectionsHandler))
+	mux.HandleFunc("/api/collections/", requireUser(collectionHandler))
+	mux.HandleFunc("/ws/highlights", requireUser(highlightsSocketHandler(allowedOrigins)))
+	mux.HandleFunc("/api/stats", requireUser(statsHandler))
+	mux.HandleFunc("/api/stats/top_verses", requireUser(topVersesHandler))
+	mux.HandleFunc("/api/heatmap", requireUser(heatmapHandler))
+	mux.HandleFunc("/api/outline", requireUser(outlineHandler))
+	mux.HandleFunc("/api/journal", requireUser(journalHandler))
+	mux.HandleFunc("/api/backup", requireAdmin(adminToken, backupHandler))
+	mux.HandleFunc("/api/admin/cleanup", requireAdmin(adminToken, cleanupHandler))
+	strongsLimiter := newIPRateLimiter(strongsRatePerSecond, strongsRateBurst)
+	mux.HandleFunc("/api/strongs_definition", rateLimit(strongsLimiter, strongsDefinitionHandler))
+	mux.HandleFunc("/api/strongs/", rateLimit(strongsLimiter, strongsNumberHandler))
+	mux.HandleFunc("/api/strongs/batch", rateLimit(newIPRateLimiter(strongsBatchRatePerSecond, strongsBatchRateBurst), strongsBatchHandler))
+	mux.HandleFunc("/api/strongs_verse", rateLimit(strongsLimiter, strongsVerseHandler))
+	mux.HandleFunc("/api/metadata", metadataHandler)
+	mux.HandleFunc("/api/audio", audioHandler)
+	mux.HandleFunc("/api/navigate", navigateHandler)
+	mux.HandleFunc("/api/openapi.json", openAPIHandler)
+	mux.HandleFunc("/api/verses", rateLimit(newIPRateLimiter(versesRatePerSecond, versesRateBurst), versesHandler))
+	mux.HandleFunc("/api/search/text", textSearchHandler)
+	mux.HandleFunc("/api/cross_references", crossReferencesHandler)
+	mux.HandleFunc("/api/original", originalHandler)
+	mux.HandleFunc("/api/", apiNotFoundHandler)
+	return mux
+}
 
-	// Start server
-	fmt.Println("Server starting on port 8080...")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Fatal(err)
+// writeJSONError sends an error response as {"error": message} so API clients
+// can always decode the body as JSON.
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// maxHighlightBodyBytes bounds the body of requests that create or update
+// highlights. Even a long note is a small fraction of this.
+const maxHighlightBodyBytes = 1 << 20
+
+// maxImportBodyBytes bounds the body of imports and bulk creates, which can
+// carry a user's whole collection of highlights and notes at once.
+const maxImportBodyBytes = 32 << 20
+
+// isBodyTooLarge reports whether err comes from reading past the limit of a
+// body wrapped in http.MaxBytesReader.
+func isBodyTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+func bodyTooLargeMessage(limit int64) string {
+	if limit < 1<<20 {
+		return fmt.Sprintf("Request body must not exceed %d KB", limit>>10)
 	}
+	return fmt.Sprintf("Request body must not exceed %d MB", limit>>20)
 }
 
 func indexHandler(w http.ResponseWriter, r *http.Request) {
-	err := tmpl.ExecuteTemplate(w, "index.html", nil)
+	t, err := templates()
+	if err == nil {
+		err = t.ExecuteTemplate(w, "index.html", nil)
+	}
 	if err != nil {
 		http.Error(w, "Failed to execute template", http.StatusInternalServerError)
-		log.Println(err)
+		slog.Error("Template error", "err", err)
 	}
 }
 
+// apiNotFoundHandler answers API paths that match no route with a JSON 404,
+// rather than letting them fall through to the index page.
+func apiNotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSONError(w, http.StatusNotFound, "Not found")
+}
+
+// healthCheckTimeout bounds how long /healthz waits on the database.
+const healthCheckTimeout = 2 * time.Second
+
+// healthHandler reports whether the database is reachable, for load
+// balancers and uptime monitors.
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := db.PingContext(ctx); err != nil {
+		slog.Error("Health check failed", "err", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "unavailable"})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
 func highlightsHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		getHighlightsHandler(w, r)
 	case http.MethodPost:
 		createHighlightHandler(w, r)
+	case http.MethodDelete:
+		deleteChapterHighlightsHandler(w, r)
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 	}
 }
 
+// getHighlightsHandler lists the user's highlights in a chapter. When chapter
+// is omitted it lists the whole book instead, ordered by chapter, verse and
+// start offset so the result reads in canonical order. hasNote=true keeps only
+// highlights with a note, and includeText=true adds the text of each
+// highlight's verse, where it has been stored.
 func getHighlightsHandler(w http.ResponseWriter, r *http.Request) {
-	translation := r.URL.Query().Get("translation")
-	bookIdStr := r.URL.Query().Get("bookId")
-	chapterStr := r.URL.Query().Get("chapter")
+	wholeBook := !r.URL.Query().Has("chapter")
 
-	if translation == "" || bookIdStr == "" || chapterStr == "" {
-		http.Error(w, "Missing required query parameters: translation, bookId, chapter", http.StatusBadRequest)
+	var translation string
+	var bookID, chapter int
+	var err error
+	if wholeBook {
+		translation, bookID, err = parseBookQuery(r)
+	} else {
+		translation, bookID, chapter, err = parseChapterQuery(r)
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	query := `SELECT id, type, verseId, start, end, note, translation, bookId, chapter FROM highlights
-	          WHERE translation = ? AND bookId = ? AND chapter = ?`
+	limit, offset, err := parsePagination(r, defaultHighlightsLimit, maxHighlightsLimit)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	rows, err := db.Query(query, translation, bookIdStr, chapterStr)
+	where := `WHERE userId = ? AND translation = ? AND bookId = ? AND deletedAt IS NULL`
+	args := []any{userIDFromContext(r.Context()), translation, bookID}
+	if !wholeBook {
+		// A span starting in an earlier chapter still shows in this one.
+		where += ` AND chapter <= ? AND endChapter >= ?`
+		args = append(args, chapter, chapter)
+	}
+	if s := r.URL.Query().Get("hasNote"); s != "" {
+		hasNote, err := strconv.ParseBool(s)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "hasNote must be true or false")
+			return
+		}
+		if hasNote {
+			where += ` AND note IS NOT NULL AND note != ''`
+		}
+	}
+
+	includeText := false
+	if s := r.URL.Query().Get("includeText"); s != "" {
+		if includeText, err = strconv.ParseBool(s); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "includeText must be true or false")
+			return
+		}
+	}
+
+	var total int
+	if err := db.QueryRowContext(r.Context(), `SELECT COUNT(*) FROM highlights `+where, args...).Scan(&total); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	orderBy := `rowid`
+	if wholeBook {
+		orderBy = `chapter, ` + verseNumberSQL + `, start, rowid`
+	}
+	switch r.URL.Query().Get("order") {
+	case "":
+	case "recent":
+		orderBy = `updatedAt DESC, rowid DESC`
+	default:
+		writeJSONError(w, http.StatusBadRequest, `order must be "recent" when given`)
+		return
+	}
+
+	columns := highlightColumns
+	if includeText {
+		columns += `, ` + verseTextColumn
+	}
+	query := `SELECT ` + columns + ` FROM highlights ` + where + ` ORDER BY ` + orderBy + ` LIMIT ? OFFSET ?`
+	rows, err := db.QueryContext(r.Context(), query, append(args, limit, offset)...)
 	if err != nil {
-		http.Error(w, "Database query failed", http.StatusInternalServerError)
-		log.Printf("DB Error: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
 		return
 	}
 	defer rows.Close()
 
 	highlights := []Highlight{}
 	for rows.Next() {
-		var h Highlight
-		var note sql.NullString // Handle possible NULL values for note
-		if err := rows.Scan(&h.ID, &h.Type, &h.VerseID, &h.Start, &h.End, &note, &h.Translation, &h.BookID, &h.Chapter); err != nil {
-			http.Error(w, "Failed to scan row", http.StatusInternalServerError)
-			log.Printf("DB Error: %v", err)
+		var s rowScanner = rows
+		var text string
+		if includeText {
+			s = extraColumns{rows, []any{&text}}
+		}
+		h, err := scanHighlight(s)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
 			return
 		}
-		if note.Valid {
-			h.Note = note.String
+		h.VerseText = text
+		highlights = append(highlights, h)
+	}
+
+	// The ETag is a hash of the exact response, so any change to the page
+	// (or to the total) produces a new one and revisits of an unchanged
+	// chapter can be answered with 304. no-cache makes browsers revalidate
+	// with If-None-Match instead of reusing a stale copy.
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(highlights)
+	sum := sha256.Sum256(append(body.Bytes(), strconv.Itoa(total)...))
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body.Bytes())
+}
+
+// etagMatches reports whether an If-None-Match header value lists etag. Weak
+// comparison is used, as If-None-Match requires, so W/ prefixes are ignored.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteChapterHighlightsHandler moves all of the user's highlights in one
+// chapter to the trash and reports how many were deleted. The same three
+// parameters as the GET are required, so a request missing one cannot clear
+// more than a chapter.
+func deleteChapterHighlightsHandler(w http.ResponseWriter, r *http.Request) {
+	translation, bookID, chapter, err := parseChapterQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID := userIDFromContext(r.Context())
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to begin transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	// Match what the GET shows, so a span starting in an earlier chapter is
+	// cleared along with the rest. Spans reaching into other chapters are
+	// read first, so the clients watching those chapters can be told.
+	const match = `userId = ? AND translation = ? AND bookId = ? AND chapter <= ? AND endChapter >= ? AND deletedAt IS NULL`
+	rows, err := tx.QueryContext(r.Context(), `SELECT `+highlightColumns+` FROM highlights
+	                       WHERE `+match+` AND (chapter < ? OR endChapter > ?)`,
+		userID, translation, bookID, chapter, chapter, chapter, chapter)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	var spans []Highlight
+	for rows.Next() {
+		h, err := scanHighlight(rows)
+		if err != nil {
+			rows.Close()
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		h.UserID = userID
+		spans = append(spans, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	result, err := tx.ExecContext(r.Context(), `UPDATE highlights SET deletedAt = ?1, updatedAt = ?1 WHERE `+match,
+		timestamp(), userID, translation, bookID, chapter, chapter)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	deleted, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to commit transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if deleted > 0 {
+		hub.publish(chapterKey{userID, translation, bookID, chapter}, HighlightEvent{Type: "cleared"})
+	}
+	for _, h := range spans {
+		hub.publishDeleted(h)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"deleted": deleted})
+}
+
+// highlightedVersesHandler returns the IDs of the verses in a chapter that
+// have at least one of the user's highlights, in verse order, for drawing
+// markers without loading every highlight. Every verse a span covers in the
+// chapter is marked, including for spans starting in an earlier chapter, as
+// the GET lists them.
+func highlightedVersesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	translation, bookID, chapter, err := parseChapterQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := `SELECT DISTINCT verseId, endVerseId FROM highlights
+	          WHERE userId = ? AND translation = ? AND bookId = ? AND chapter <= ? AND endChapter >= ? AND deletedAt IS NULL`
+	rows, err := db.QueryContext(r.Context(), query, userIDFromContext(r.Context()), translation, bookID, chapter, chapter)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	marked := map[int]bool{}
+	var unnumbered []string // verseIds the frontend did not generate, listed last
+	for rows.Next() {
+		var start, end string
+		if err := rows.Scan(&start, &end); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		_, startChapter, first, ok := parseVerseID(start)
+		if !ok {
+			unnumbered = append(unnumbered, start)
+			continue
+		}
+		if startChapter < chapter {
+			first = 1
+		}
+		last := first
+		if _, endChapter, endVerse, ok := parseVerseID(end); ok {
+			last = endVerse
+			if endChapter > chapter {
+				last = metadataVerseCount(bookID, chapter)
+			}
+		}
+		for v := first; v <= last; v++ {
+			marked[v] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	verseIDs := []string{}
+	for _, v := range slices.Sorted(maps.Keys(marked)) {
+		verseIDs = append(verseIDs, verseID(bookID, chapter, v))
+	}
+	slices.Sort(unnumbered)
+	verseIDs = append(verseIDs, slices.Compact(unnumbered)...)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verseIDs)
+}
+
+// verseNumberSQL extracts the verse number from a highlight's verseId
+// ("verse-{bookId}-{chapter}-{verse}"), so verse 10 sorts after verse 9.
+const verseNumberSQL = `CAST(replace(verseId, 'verse-' || bookId || '-' || chapter || '-', '') AS INTEGER)`
+
+// parseChapterQuery reads the translation, bookId and chapter query
+// parameters that identify a chapter. bookId and chapter must be positive
+// integers. The returned error is suitable to send back to the client.
+func parseChapterQuery(r *http.Request) (translation string, bookID, chapter int, err error) {
+	chapterStr := r.URL.Query().Get("chapter")
+	if r.URL.Query().Get("translation") == "" || r.URL.Query().Get("bookId") == "" || chapterStr == "" {
+		return "", 0, 0, errors.New("Missing required query parameters: translation, bookId, chapter")
+	}
+	if translation, bookID, err = parseBookQuery(r); err != nil {
+		return "", 0, 0, err
+	}
+	if chapter, err = strconv.Atoi(chapterStr); err != nil || chapter < 1 {
+		return "", 0, 0, errors.New("chapter must be a positive integer")
+	}
+	return translation, bookID, chapter, nil
+}
+
+// parseBookQuery reads the translation and bookId query parameters that
+// identify a book, like parseChapterQuery without the chapter.
+func parseBookQuery(r *http.Request) (translation string, bookID int, err error) {
+	translation = r.URL.Query().Get("translation")
+	bookIDStr := r.URL.Query().Get("bookId")
+
+	if translation == "" || bookIDStr == "" {
+		return "", 0, errors.New("Missing required query parameters: translation, bookId")
+	}
+	if bookID, err = strconv.Atoi(bookIDStr); err != nil || bookID < 1 {
+		return "", 0, errors.New("bookId must be a positive integer")
+	}
+	return translation, bookID, nil
+}
+
+// Page sizes for getHighlightsHandler.
+const (
+	defaultHighlightsLimit = 200
+	maxHighlightsLimit     = 500
+)
+
+// parsePagination reads the optional limit and offset query parameters.
+// A missing limit means defaultLimit and a larger one is capped at maxLimit.
+// The returned error is suitable to send back to the client.
+func parsePagination(r *http.Request, defaultLimit, maxLimit int) (limit, offset int, err error) {
+	limit = defaultLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 0 {
+			return 0, 0, errors.New("limit must be a non-negative integer")
+		}
+		limit = min(limit, maxLimit)
+	}
+	if s := r.URL.Query().Get("offset"); s != "" {
+		offset, err = strconv.Atoi(s)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
+
+// maxSearchResults caps how many highlights a single search page returns.
+const maxSearchResults = 100
+
+// likeEscaper escapes LIKE wildcards so a search term is matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// searchHighlightsHandler finds highlights whose note contains q, optionally
+// restricted to one translation. Results are paged with offset, at most
+// maxSearchResults at a time, in canonical verse order.
+func searchHighlightsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		writeJSONError(w, http.StatusBadRequest, "Missing required query parameter: q")
+		return
+	}
+
+	offset := 0
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		var err error
+		offset, err = strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			writeJSONError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+	}
+
+	query := `SELECT ` + highlightColumns + ` FROM highlights WHERE userId = ? AND deletedAt IS NULL AND note LIKE ? ESCAPE '\'`
+	args := []any{userIDFromContext(r.Context()), "%" + likeEscaper.Replace(q) + "%"}
+	if translation := r.URL.Query().Get("translation"); translation != "" {
+		query += ` AND translation = ?`
+		args = append(args, translation)
+	}
+	query += ` ORDER BY bookId, chapter, ` + verseNumberSQL + `, start LIMIT ? OFFSET ?`
+	args = append(args, maxSearchResults, offset)
+
+	rows, err := db.QueryContext(r.Context(), query, args...)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	highlights := []Highlight{}
+	for rows.Next() {
+		h, err := scanHighlight(rows)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
 		}
 		highlights = append(highlights, h)
 	}
@@ -133,194 +851,770 @@ func getHighlightsHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(highlights)
 }
 
-func createHighlightHandler(w http.ResponseWriter, r *http.Request) {
-	var h Highlight
-	if err := json.NewDecoder(r.Body).Decode(&h); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+// Page sizes for the recent highlights feed.
+const (
+	defaultRecentLimit = 20
+	maxRecentLimit     = 100
+)
+
+// recentHighlightsHandler returns the current user's most recently created
+// highlights across every translation and book, newest first.
+func recentHighlightsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	query := `INSERT INTO highlights (id, type, verseId, start, end, note, translation, bookId, chapter)
-	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	limit, offset, err := parsePagination(r, defaultRecentLimit, maxRecentLimit)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	stmt, err := db.Prepare(query)
+	query := `SELECT ` + highlightColumns + ` FROM highlights WHERE userId = ? AND deletedAt IS NULL
+	          ORDER BY createdAt DESC, rowid DESC LIMIT ? OFFSET ?`
+	rows, err := db.QueryContext(r.Context(), query, userIDFromContext(r.Context()), limit, offset)
 	if err != nil {
-		http.Error(w, "Failed to prepare statement", http.StatusInternalServerError)
-		log.Printf("DB Error: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
 		return
 	}
-	defer stmt.Close()
+	defer rows.Close()
+
+	highlights := []Highlight{}
+	for rows.Next() {
+		h, err := scanHighlight(rows)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		highlights = append(highlights, h)
+	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(highlights)
+}
+
+const insertHighlightSQL = `INSERT INTO highlights (id, type, verseId, endVerseId, start, end, note, translation, bookId, chapter, endChapter, color, strongsNumber, osisRef, needsReview, userId, createdAt, updatedAt)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// insertHighlightArgs returns the arguments for insertHighlightSQL, storing an
+// empty note as NULL. endChapter is derived from endVerseId so chapter queries
+// can find spans that end in a later chapter, and osisRef from verseId.
+func insertHighlightArgs(h Highlight) []any {
 	var note sql.NullString
 	if h.Note != "" {
 		note = sql.NullString{String: h.Note, Valid: true}
 	}
+	return []any{h.ID, h.Type, h.VerseID, h.EndVerseID, h.Start, h.End, note, h.Translation, h.BookID, h.Chapter, highlightEndChapter(h), h.Color, h.StrongsNumber, highlightOSISRef(h), h.NeedsReview, h.UserID, h.CreatedAt, h.UpdatedAt}
+}
+
+// highlightEndChapter returns the chapter h ends in: that of its endVerseId,
+// or the chapter it starts in when it does not span verses.
+func highlightEndChapter(h Highlight) int {
+	if _, c, _, ok := parseVerseID(h.EndVerseID); ok {
+		return c
+	}
+	return h.Chapter
+}
+
+// highlightOSISRef returns the OSIS reference of the verse h starts in, or ""
+// if its verseId is not one the frontend generates.
+func highlightOSISRef(h Highlight) string {
+	bookID, chapter, verse, ok := parseVerseID(h.VerseID)
+	if !ok || bookID != h.BookID || chapter != h.Chapter {
+		return ""
+	}
+	return toOSIS(bookID, chapter, verse)
+}
+
+// timestamp returns the current time in the RFC3339 form stored in
+// createdAt and updatedAt.
+func timestamp() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// stampHighlight marks h as written now, keeping a createdAt it already has
+// so that imported highlights retain their original creation time. It also
+// fills in osisRef to match what insertHighlightArgs stores.
+func stampHighlight(h *Highlight) {
+	h.OSISRef = highlightOSISRef(*h)
+	h.UpdatedAt = timestamp()
+	if h.CreatedAt == "" {
+		h.CreatedAt = h.UpdatedAt
+	}
+}
+
+// validHighlightTypes lists the highlight types the frontend knows how to
+// render. "highlight-only" is a plain colored span and "note" carries text.
+var validHighlightTypes = []string{"highlight-only", "note", "underline"}
+
+func isValidHighlightType(t string) bool {
+	return slices.Contains(validHighlightTypes, t)
+}
+
+func invalidTypeMessage(t string) string {
+	return fmt.Sprintf("Invalid highlight type %q; valid types are: %s", t, strings.Join(validHighlightTypes, ", "))
+}
+
+// parseVerseID splits a verseId of the form "verse-{bookId}-{chapter}-{verse}".
+func parseVerseID(id string) (bookID, chapter, verse int, ok bool) {
+	parts := strings.Split(id, "-")
+	if len(parts) != 4 || parts[0] != "verse" {
+		return 0, 0, 0, false
+	}
+	var err1, err2, err3 error
+	bookID, err1 = strconv.Atoi(parts[1])
+	chapter, err2 = strconv.Atoi(parts[2])
+	verse, err3 = strconv.Atoi(parts[3])
+	return bookID, chapter, verse, err1 == nil && err2 == nil && err3 == nil
+}
+
+// spanError checks the endVerseId of a highlight spanning several verses. The
+// span must stay within the book and end after the verse it starts in; it may
+// cross into later chapters. It returns "" for a valid or single-verse
+// highlight.
+func spanError(h Highlight) string {
+	if h.EndVerseID == "" || h.EndVerseID == h.VerseID {
+		return ""
+	}
+	endBook, endChapter, endVerse, ok := parseVerseID(h.EndVerseID)
+	if !ok {
+		return fmt.Sprintf("Invalid endVerseId %q; expected verse-{bookId}-{chapter}-{verse}", h.EndVerseID)
+	}
+	if endBook != h.BookID {
+		return "endVerseId must be in the same book as the highlight"
+	}
+	_, startChapter, startVerse, ok := parseVerseID(h.VerseID)
+	if !ok || startChapter != h.Chapter {
+		return "verseId must be in the highlight's chapter when endVerseId is set"
+	}
+	if endChapter < startChapter || (endChapter == startChapter && endVerse < startVerse) {
+		return "endVerseId must not come before verseId"
+	}
+	return ""
+}
 
-	_, err = stmt.Exec(h.ID, h.Type, h.VerseID, h.Start, h.End, note, h.Translation, h.BookID, h.Chapter)
+// maxHighlightIDLength bounds highlight IDs; the frontend's are far shorter.
+const maxHighlightIDLength = 128
+
+// highlightIDFromPath returns the highlight ID following prefix in the
+// request path. The ID is taken from the escaped path and decoded once, so an
+// encoded slash cannot smuggle in another path segment, and IDs that are
+// empty, too long or contain slashes are rejected. The returned error is
+// suitable to send back to the client.
+func highlightIDFromPath(r *http.Request, prefix string) (string, error) {
+	return highlightIDFromPathSegment(r, prefix, "")
+}
+
+// highlightIDFromPathSegment is highlightIDFromPath for paths that continue
+// after the ID, such as /api/highlights/{id}/restore, with suffix being the
+// rest of the path.
+func highlightIDFromPathSegment(r *http.Request, prefix, suffix string) (string, error) {
+	escaped, ok := strings.CutPrefix(r.URL.EscapedPath(), prefix)
+	if ok {
+		escaped, ok = strings.CutSuffix(escaped, suffix)
+	}
+	if !ok || escaped == "" {
+		return "", errors.New("Missing highlight ID")
+	}
+	id, err := url.PathUnescape(escaped)
 	if err != nil {
-		http.Error(w, "Failed to execute statement", http.StatusInternalServerError)
-		log.Printf("DB Error: %v", err)
+		return "", errors.New("Malformed highlight ID")
+	}
+	if strings.Contains(id, "/") {
+		return "", errors.New("Highlight ID must not contain slashes")
+	}
+	if len(id) > maxHighlightIDLength {
+		return "", fmt.Errorf("Highlight ID must be at most %d bytes", maxHighlightIDLength)
+	}
+	return id, nil
+}
+
+// highlightRule is one of the checks a stored highlight must pass, named so
+// the admin cleanup can report which rule each removed row broke.
+type highlightRule struct {
+	name  string
+	check func(h Highlight) error
+}
+
+// highlightRules are applied in order by validate.
+var highlightRules = []highlightRule{
+	{"id", func(h Highlight) error {
+		switch {
+		case h.ID == "":
+			return errors.New("id is required")
+		case len(h.ID) > maxHighlightIDLength || strings.Contains(h.ID, "/"):
+			return fmt.Errorf("id must be at most %d bytes and contain no slashes", maxHighlightIDLength)
+		}
+		return nil
+	}},
+	{"verseId", func(h Highlight) error {
+		if h.VerseID == "" {
+			return errors.New("verseId is required")
+		}
+		return nil
+	}},
+	{"translation", func(h Highlight) error {
+		if h.Translation == "" {
+			return errors.New("translation is required")
+		}
+		return nil
+	}},
+	{"type", func(h Highlight) error {
+		if !isValidHighlightType(h.Type) {
+			return errors.New(invalidTypeMessage(h.Type))
+		}
+		return nil
+	}},
+	{"range", func(h Highlight) error {
+		switch {
+		case h.Start < 0:
+			return errors.New("start must not be negative")
+		case h.End < h.Start:
+			return errors.New("end must not be before start")
+		}
+		return nil
+	}},
+	{"bookId", func(h Highlight) error {
+		if h.BookID < 1 || h.BookID > len(metadata.Books) {
+			return fmt.Errorf("bookId must be between 1 and %d", len(metadata.Books))
+		}
+		return nil
+	}},
+	{"chapter", func(h Highlight) error {
+		if h.Chapter < 1 {
+			return errors.New("chapter must be a positive integer")
+		}
+		return nil
+	}},
+	{"span", func(h Highlight) error {
+		if msg := spanError(h); msg != "" {
+			return errors.New(msg)
+		}
+		return nil
+	}},
+}
+
+// brokenRule returns the first of highlightRules that h fails and its error,
+// or a nil error if h passes them all.
+func brokenRule(h Highlight) (string, error) {
+	for _, rule := range highlightRules {
+		if err := rule.check(h); err != nil {
+			return rule.name, err
+		}
+	}
+	return "", nil
+}
+
+// validate checks that h can be placed in the text: the required fields are
+// set, its offsets form a range and it refers to a real book and chapter. It
+// returns the first problem found, worded for the client. A valid h has its
+// color normalized for storing.
+func (h *Highlight) validate() error {
+	if _, err := brokenRule(*h); err != nil {
+		return err
+	}
+	color, err := normalizeColor(h.Color)
+	if err != nil {
+		return err
+	}
+	h.Color = color
+	return nil
+}
+
+// isPrimaryKeyViolation reports whether err is SQLite rejecting a duplicate
+// primary key, which for highlights means the client reused an ID.
+func isPrimaryKeyViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+}
+
+// reviveTrashedHighlight overwrites the trashed highlight with h's ID and
+// user with h and restores it, keeping its original createdAt in h. This is
+// how a client undoes a delete: by posting back the highlight DELETE
+// returned. It reports false, changing nothing, if the ID belongs to a
+// highlight that is not in the trash or to another user.
+func reviveTrashedHighlight(ctx context.Context, tx *sql.Tx, h *Highlight) (bool, error) {
+	res, err := tx.ExecContext(ctx, upsertHighlightSQL+` AND highlights.deletedAt IS NOT NULL`, insertHighlightArgs(*h)...)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	return true, tx.QueryRowContext(ctx, `SELECT createdAt FROM highlights WHERE id = ?`, h.ID).Scan(&h.CreatedAt)
+}
+
+// createHighlightHandler stores a new highlight and returns it. With
+// merge=true, existing highlights it overlaps are folded into it first; see
+// mergeOverlapping. A request repeating the Idempotency-Key of an earlier one
+// gets that request's response back and changes nothing. Reusing the ID of one
+// of the user's trashed highlights restores it; see reviveTrashedHighlight.
+func createHighlightHandler(w http.ResponseWriter, r *http.Request) {
+	key, err := idempotencyKey(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	var h Highlight
+	r.Body = http.MaxBytesReader(w, r.Body, maxHighlightBodyBytes)
+	err = json.NewDecoder(r.Body).Decode(&h)
+	if isBodyTooLarge(err) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, bodyTooLargeMessage(maxHighlightBodyBytes))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	h.UserID = userIDFromContext(r.Context())
+
+	if err := h.validate(); err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	var ok bool
+	if h.StrongsNumber, ok = normalizeStrongsNumber(h.StrongsNumber); !ok {
+		writeJSONError(w, http.StatusUnprocessableEntity, invalidStrongsNumberMessage)
+		return
+	}
+	msg, err := applyPalette(r.Context(), db, &h)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if msg != "" {
+		writeJSONError(w, http.StatusUnprocessableEntity, msg)
+		return
+	}
+	merge := false
+	if s := r.URL.Query().Get("merge"); s != "" {
+		if merge, err = strconv.ParseBool(s); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "merge must be true or false")
+			return
+		}
+	}
+
+	h.Tags = cleanTags(h.Tags)
+	h.CreatedAt = ""
+	stampHighlight(&h)
+
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to begin transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	now := time.Now()
+	if key != "" {
+		body, found, err := idempotentResponse(r.Context(), tx, h.UserID, key, now)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		if found {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+			return
+		}
+	}
+
+	var merged []Highlight
+	if merge {
+		if merged, err = mergeOverlapping(r.Context(), tx, &h); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to merge highlights")
+			slog.Error("Database error", "err", err)
+			return
+		}
+	}
+
+	_, err = tx.StmtContext(r.Context(), insertHighlightStmt).ExecContext(r.Context(), insertHighlightArgs(h)...)
+	if isPrimaryKeyViolation(err) {
+		var revived bool
+		if revived, err = reviveTrashedHighlight(r.Context(), tx, &h); err == nil && !revived {
+			writeJSONError(w, http.StatusConflict, fmt.Sprintf("A highlight with ID %q already exists", h.ID))
+			return
+		}
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	if err := setHighlightTags(r.Context(), tx, h); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to save tags")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	body, err := json.Marshal(h)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to encode highlight")
+		slog.Error("Encoding highlight failed", "err", err)
+		return
+	}
+	body = append(body, '\n')
+	if key != "" {
+		if err := storeIdempotentResponse(r.Context(), tx, h.UserID, key, body, now); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+			slog.Error("Database error", "err", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to commit transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	for _, old := range merged {
+		hub.publishDeleted(old)
+	}
+	hub.publishHighlight("created", h)
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(h)
+	w.Write(body)
 }
 
-func deleteHighlightHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// createHighlightsBulkHandler inserts a JSON array of highlights in a single
+// transaction. Either every highlight is stored or none are.
+func createHighlightsBulkHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	id := strings.TrimPrefix(r.URL.Path, "/api/highlights/delete/")
-	if id == "" {
-		http.Error(w, "Missing highlight ID", http.StatusBadRequest)
+	var raw []json.RawMessage
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+	err := json.NewDecoder(r.Body).Decode(&raw)
+	if isBodyTooLarge(err) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, bodyTooLargeMessage(maxImportBodyBytes))
 		return
 	}
-
-	query := `DELETE FROM highlights WHERE id = ?`
-	stmt, err := db.Prepare(query)
 	if err != nil {
-		http.Error(w, "Failed to prepare statement", http.StatusInternalServerError)
-		log.Printf("DB Error: %v", err)
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body: expected a JSON array of highlights")
 		return
 	}
-	defer stmt.Close()
 
-	result, err := stmt.Exec(id)
+	highlights := make([]Highlight, len(raw))
+	for i, msg := range raw {
+		if err := json.Unmarshal(msg, &highlights[i]); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("Invalid highlight at index %d", i))
+			return
+		}
+		if err := highlights[i].validate(); err != nil {
+			writeJSONError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Highlight at index %d: %s", i, err))
+			return
+		}
+		var ok bool
+		if highlights[i].StrongsNumber, ok = normalizeStrongsNumber(highlights[i].StrongsNumber); !ok {
+			writeJSONError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Highlight at index %d: %s", i, invalidStrongsNumberMessage))
+			return
+		}
+		highlights[i].UserID = userIDFromContext(r.Context())
+		msg, err := applyPalette(r.Context(), db, &highlights[i])
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		if msg != "" {
+			writeJSONError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Highlight at index %d: %s", i, msg))
+			return
+		}
+		highlights[i].CreatedAt = ""
+		stampHighlight(&highlights[i])
+	}
+
+	tx, err := db.BeginTx(r.Context(), nil)
 	if err != nil {
-		http.Error(w, "Failed to execute statement", http.StatusInternalServerError)
-		log.Printf("DB Error: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to begin transaction")
+		slog.Error("Database error", "err", err)
 		return
 	}
+	defer tx.Rollback() // No-op once the transaction has been committed
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		http.Error(w, "Highlight not found", http.StatusNotFound)
+	stmt := tx.StmtContext(r.Context(), insertHighlightStmt)
+	defer stmt.Close()
+
+	for i, h := range highlights {
+		_, err := stmt.ExecContext(r.Context(), insertHighlightArgs(h)...)
+		if isPrimaryKeyViolation(err) {
+			writeJSONError(w, http.StatusConflict, fmt.Sprintf("A highlight with ID %q already exists (index %d)", h.ID, i))
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to insert highlight at index %d", i))
+			slog.Error("Database error", "err", err)
+			return
+		}
+		if err := setHighlightTags(r.Context(), tx, h); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to save tags for highlight at index %d", i))
+			slog.Error("Database error", "err", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to commit transaction")
+		slog.Error("Database error", "err", err)
 		return
 	}
+	for _, h := range highlights {
+		hub.publishHighlight("created", h)
+	}
 
-	w.WriteHeader(http.StatusNoContent)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int{"inserted": len(highlights)})
 }
 
-// StrongsDefinition holds the scraped definition data.
-type StrongsDefinition struct {
-	StrongsNumber   string `json:"strongsNumber"`
-	Lexeme          string `json:"lexeme"`
-	Transliteration string `json:"transliteration"`
-	Definition      string `json:"definition"`
+// highlightHandler routes requests for a single highlight addressed as
+// /api/highlights/{id}, /api/highlights/{id}/restore and
+// /api/highlights/{id}/history.
+func highlightHandler(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.EscapedPath(), restoreSuffix) {
+		restoreHighlightHandler(w, r)
+		return
+	}
+	if strings.HasSuffix(r.URL.EscapedPath(), historySuffix) {
+		noteHistoryHandler(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		getHighlightHandler(w, r)
+	case http.MethodPut:
+		updateHighlightHandler(w, r)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
 }
 
-// strongsDefinitionHandler scrapes Blue Letter Bible for a Strong's definition.
-// It is brittle and depends on the HTML structure of blueletterbible.org.
-func strongsDefinitionHandler(w http.ResponseWriter, r *http.Request) {
-	// 1. Get query parameters
-	word := r.URL.Query().Get("word")
-	translation := r.URL.Query().Get("translation")
-	bookName := r.URL.Query().Get("bookName")
-	chapter := r.URL.Query().Get("chapter")
-	verse := r.URL.Query().Get("verse")
+// getHighlightHandler returns a single highlight, for deep links and edit
+// forms that start from an ID.
+func getHighlightHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := highlightIDFromPath(r, "/api/highlights/")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	if word == "" || translation == "" || bookName == "" || chapter == "" || verse == "" {
-		http.Error(w, "Missing required query parameters", http.StatusBadRequest)
+	h, err := scanHighlight(selectHighlightStmt.QueryRowContext(r.Context(), id, userIDFromContext(r.Context())))
+	if err == sql.ErrNoRows {
+		writeJSONError(w, http.StatusNotFound, "Highlight not found")
 		return
 	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h)
+}
 
-	// 2. Construct the search URL for Blue Letter Bible's interlinear view
-	verseRef := fmt.Sprintf("%s+%s:%s", bookName, chapter, verse)
-	// Note: The 'Criteria' is the word we are looking for. 'fromverse' gives it context.
-	searchURL := fmt.Sprintf("https://www.blueletterbible.org/search/preSearch.cfm?Criteria=%s&t=%s&ss=1&source=from_interlinear&fromverse=%s", url.QueryEscape(word), translation, url.QueryEscape(verseRef))
+// highlightUpdate is the partial body accepted by updateHighlightHandler.
+// Note, Tags and NeedsReview are pointers so that an omitted field can be told
+// apart from one being cleared with "", [] or false.
+type highlightUpdate struct {
+	Note        *string   `json:"note"`
+	Type        string    `json:"type"`
+	Color       string    `json:"color"`
+	Tags        *[]string `json:"tags"`
+	NeedsReview *bool     `json:"needsReview"`
+}
 
-	// 3. Make the first request to get the interlinear page and find the Strong's link
-	res, err := http.Get(searchURL)
+// updateHighlightHandler changes the note, type, color and tags of an existing
+// highlight in place so that it keeps its original ID. Fields left out of the
+// body are preserved; an empty note clears it back to NULL. A note that is
+// replaced or cleared is kept in the highlight's note history.
+func updateHighlightHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := highlightIDFromPath(r, "/api/highlights/")
 	if err != nil {
-		http.Error(w, "Failed to fetch from Blue Letter Bible", http.StatusInternalServerError)
-		log.Printf("BLB request failed: %v for url %s", err, searchURL)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer res.Body.Close()
 
-	if res.StatusCode != 200 {
-		http.Error(w, fmt.Sprintf("Blue Letter Bible returned non-200 status: %d", res.StatusCode), http.StatusBadGateway)
-		log.Printf("BLB status code: %d for URL: %s", res.StatusCode, searchURL)
+	var u highlightUpdate
+	r.Body = http.MaxBytesReader(w, r.Body, maxHighlightBodyBytes)
+	err = json.NewDecoder(r.Body).Decode(&u)
+	if isBodyTooLarge(err) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, bodyTooLargeMessage(maxHighlightBodyBytes))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if u.Type != "" && !isValidHighlightType(u.Type) {
+		writeJSONError(w, http.StatusUnprocessableEntity, invalidTypeMessage(u.Type))
+		return
+	}
+	if u.Color, err = normalizeColor(u.Color); err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
 		return
 	}
 
-	doc, err := goquery.NewDocumentFromReader(res.Body)
+	var note sql.NullString
+	if u.Note != nil && *u.Note != "" {
+		note = sql.NullString{String: *u.Note, Valid: true}
+	}
+
+	query := `UPDATE highlights SET
+	              note = CASE WHEN ? THEN ? ELSE note END,
+	              type = COALESCE(NULLIF(?, ''), type),
+	              color = COALESCE(NULLIF(?, ''), color),
+	              needsReview = COALESCE(?, needsReview),
+	              updatedAt = ?
+	          WHERE id = ? AND userId = ? AND deletedAt IS NULL`
+	userID := userIDFromContext(r.Context())
+	tx, err := db.BeginTx(r.Context(), nil)
 	if err != nil {
-		http.Error(w, "Failed to parse BLB response", http.StatusInternalServerError)
-		log.Printf("goquery parsing failed: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to begin transaction")
+		slog.Error("Database error", "err", err)
 		return
 	}
+	defer tx.Rollback() // No-op once the transaction has been committed
 
-	// 4. Find the link to the Strong's definition.
-	var definitionURL string
-	doc.Find("td.calque-processed").EachWithBreak(func(i int, s *goquery.Selection) bool {
-		// Use Contains because the word might have punctuation (e.g., "men.")
-		if strings.Contains(strings.ToLower(s.Text()), strings.ToLower(word)) {
-			// Found the word, now find the Strong's link in the same row (parent tr).
-			link, found := s.Parent().Find("td.strongs-num-unprocessed a").Attr("href")
-			if found {
-				definitionURL = "https://www.blueletterbible.org" + link
-				return false // Stop iterating
-			}
+	now := timestamp()
+	if u.Note != nil {
+		if err := recordNoteHistory(r.Context(), tx, id, userID, note, now); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+			slog.Error("Database error", "err", err)
+			return
 		}
-		return true // Continue iterating
-	})
+	}
 
-	if definitionURL == "" {
-		http.Error(w, "Could not find Strong's number link on Blue Letter Bible. The site's structure may have changed, or the word was not found in the interlinear view for that verse.", http.StatusNotFound)
-		log.Printf("Could not find Strong's link for word '%s' at URL: %s", word, searchURL)
+	result, err := tx.ExecContext(r.Context(), query, u.Note != nil, note, u.Type, u.Color, u.NeedsReview, now, id, userID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
 		return
 	}
 
-	// 5. Make the second request to the definition page
-	defRes, err := http.Get(definitionURL)
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		writeJSONError(w, http.StatusNotFound, "Highlight not found")
+		return
+	}
+
+	if u.Tags != nil {
+		if err := setHighlightTags(r.Context(), tx, Highlight{ID: id, UserID: userID, Tags: *u.Tags}); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to save tags")
+			slog.Error("Database error", "err", err)
+			return
+		}
+	}
+
+	// Check the highlight as it now stands before committing, so a change
+	// cannot leave it in a state create would have rejected.
+	updated, err := scanHighlight(tx.StmtContext(r.Context(), selectHighlightStmt).QueryRowContext(r.Context(), id, userID))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	updated.UserID = userID
+	if err := updated.validate(); err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	color := updated.Color
+	msg, err := applyPalette(r.Context(), tx, &updated)
 	if err != nil {
-		http.Error(w, "Failed to fetch definition page from BLB", http.StatusInternalServerError)
-		log.Printf("BLB definition page request failed: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if msg != "" {
+		writeJSONError(w, http.StatusUnprocessableEntity, msg)
 		return
 	}
-	defer defRes.Body.Close()
+	// A highlight left without a color takes the palette's, as on create.
+	if updated.Color != color {
+		if _, err := tx.ExecContext(r.Context(), `UPDATE highlights SET color = ? WHERE id = ? AND userId = ?`, updated.Color, id, userID); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+			slog.Error("Database error", "err", err)
+			return
+		}
+	}
 
-	if defRes.StatusCode != 200 {
-		http.Error(w, fmt.Sprintf("BLB definition page returned non-200 status: %d", defRes.StatusCode), http.StatusBadGateway)
+	if err := tx.Commit(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to commit transaction")
+		slog.Error("Database error", "err", err)
 		return
 	}
+	hub.publishHighlight("updated", updated)
 
-	defDoc, err := goquery.NewDocumentFromReader(defRes.Body)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// deleteHighlightHandler moves a highlight to the trash, from which it can be
+// restored until purgeTrash removes it for good.
+func deleteHighlightHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := highlightIDFromPath(r, "/api/highlights/delete/")
 	if err != nil {
-		http.Error(w, "Failed to parse BLB definition response", http.StatusInternalServerError)
-		log.Printf("goquery definition parsing failed: %v", err)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// 6. Scrape the definition details from the lexicon page.
-	strongsNumber := defDoc.Find("#lexicon-head h1").Text()
-	lexeme := defDoc.Find(".lex-lemma-head .lexeme").First().Text()
-	transliteration := defDoc.Find(".lex-lemma-head .translit").First().Text()
+	userID := userIDFromContext(r.Context())
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to begin transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
 
-	var definitionBuilder strings.Builder
-	defDoc.Find("#lexDef p").Each(func(i int, s *goquery.Selection) {
-		definitionBuilder.WriteString(s.Text())
-		definitionBuilder.WriteString("\n\n") // Add paragraphs for readability
-	})
+	// Read the row first so it can be returned, letting the client offer an
+	// undo through the restore endpoint.
+	deleted, err := scanHighlight(tx.StmtContext(r.Context(), selectHighlightStmt).QueryRowContext(r.Context(), id, userID))
+	if err == sql.ErrNoRows {
+		writeJSONError(w, http.StatusNotFound, "Highlight not found")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	deleted.UserID = userID
+	deleted.DeletedAt = timestamp()
+	deleted.UpdatedAt = deleted.DeletedAt
 
-	definition := strings.TrimSpace(definitionBuilder.String())
-	if definition == "" {
-		// Fallback for different structures (sometimes content is not in 'p' tags)
-		definition = strings.TrimSpace(defDoc.Find("#lexDef").First().Text())
+	if _, err := tx.StmtContext(r.Context(), trashHighlightStmt).ExecContext(r.Context(), deleted.DeletedAt, id, userID); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
 	}
 
-	// 7. Send the response
-	response := StrongsDefinition{
-		StrongsNumber:   strings.TrimSpace(strongsNumber),
-		Lexeme:          strings.TrimSpace(lexeme),
-		Transliteration: strings.TrimSpace(transliteration),
-		Definition:      definition,
+	if err := tx.Commit(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to commit transaction")
+		slog.Error("Database error", "err", err)
+		return
 	}
+	hub.publishDeleted(deleted)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(deleted)
 }
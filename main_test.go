@@ -0,0 +1,585 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// Migrations, warnings and failed lookups log as they would in
+	// production; keep that out of the test output.
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// useTestDB points db at a fresh in-memory database, migrated and with the
+// shared statements prepared, for the rest of the test.
+func useTestDB(t *testing.T) {
+	t.Helper()
+	conn, err := openDB(":memory:")
+	if err != nil {
+		t.Fatalf("openDB: %v", err)
+	}
+	old := db
+	db = conn
+	t.Cleanup(func() {
+		closeStatements()
+		conn.Close()
+		db = old
+	})
+}
+
+// testMux routes the account and highlight endpoints the way main does.
+func testMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/register", registerHandler)
+	mux.HandleFunc("/api/login", loginHandler)
+	mux.HandleFunc("/api/logout", logoutHandler)
+	mux.HandleFunc("/api/me", requireUser(currentUserHandler))
+	mux.HandleFunc("/api/highlights", requireUser(highlightsHandler))
+	mux.HandleFunc("/api/highlights/", requireUser(highlightHandler))
+	mux.HandleFunc("/api/highlights/bulk", requireUser(createHighlightsBulkHandler))
+	mux.HandleFunc("/api/highlights/delete/", requireUser(deleteHighlightHandler))
+	mux.HandleFunc("/api/highlights/verses", requireUser(highlightedVersesHandler))
+	mux.HandleFunc("/api/highlights/markdown", requireUser(markdownHighlightsHandler))
+	mux.HandleFunc("/api/highlights/import", requireUser(importHighlightsHandler))
+	mux.HandleFunc("/api/import/youversion", requireUser(importYouVersionHandler))
+	mux.HandleFunc("/api/highlights/copy", requireUser(copyHighlightsHandler))
+	mux.HandleFunc("/api/palette", requireUser(paletteHandler))
+	mux.HandleFunc("/api/", apiNotFoundHandler)
+	return mux
+}
+
+// serve sends a request with an optional JSON body and session cookie to h.
+func serve(h http.Handler, method, target, body string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// signUp registers username and returns its session cookie.
+func signUp(t *testing.T, h http.Handler, username string) *http.Cookie {
+	t.Helper()
+	rec := serve(h, http.MethodPost, "/api/register", `{"username":"`+username+`","password":"correct horse"}`, nil)
+	if rec.Code != http.StatusCreated && rec.Code != http.StatusOK {
+		t.Fatalf("register %s: status %d: %s", username, rec.Code, rec.Body)
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == sessionCookieName {
+			return c
+		}
+	}
+	t.Fatalf("register %s: no session cookie", username)
+	return nil
+}
+
+// testHighlight is a valid highlight body with the given ID on Genesis 1:1.
+func testHighlight(id string) string {
+	return `{"id":"` + id + `","type":"highlight-only","verseId":"verse-1-1-1","start":0,"end":5,
+		"translation":"KJV","bookId":1,"chapter":1,"color":"#ffff00"}`
+}
+
+func TestAuth(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	cookie := signUp(t, mux, "ann")
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		cookie *http.Cookie
+		want   int
+	}{
+		{"me with session", http.MethodGet, "/api/me", "", cookie, http.StatusOK},
+		{"me without session", http.MethodGet, "/api/me", "", nil, http.StatusUnauthorized},
+		{"me with bogus session", http.MethodGet, "/api/me", "", &http.Cookie{Name: sessionCookieName, Value: "nope"}, http.StatusUnauthorized},
+		{"highlights without session", http.MethodGet, "/api/highlights?translation=KJV&bookId=1&chapter=1", "", nil, http.StatusUnauthorized},
+		{"duplicate username", http.MethodPost, "/api/register", `{"username":"ann","password":"another one"}`, nil, http.StatusConflict},
+		{"register without password", http.MethodPost, "/api/register", `{"username":"bob"}`, nil, http.StatusBadRequest},
+		{"register with GET", http.MethodGet, "/api/register", "", nil, http.StatusMethodNotAllowed},
+		{"register with a password bcrypt cannot hash", http.MethodPost, "/api/register", `{"username":"bob","password":"` + strings.Repeat("x", 73) + `"}`, nil, http.StatusBadRequest},
+		{"register with an oversized body", http.MethodPost, "/api/register", `{"username":"bob","password":"` + strings.Repeat("x", 20<<10) + `"}`, nil, http.StatusRequestEntityTooLarge},
+		{"login with an oversized body", http.MethodPost, "/api/login", `{"username":"ann","password":"` + strings.Repeat("x", 20<<10) + `"}`, nil, http.StatusRequestEntityTooLarge},
+		{"login", http.MethodPost, "/api/login", `{"username":"ann","password":"correct horse"}`, nil, http.StatusOK},
+		{"login with wrong password", http.MethodPost, "/api/login", `{"username":"ann","password":"wrong"}`, nil, http.StatusUnauthorized},
+		{"login as unknown user", http.MethodPost, "/api/login", `{"username":"zed","password":"correct horse"}`, nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(mux, tt.method, tt.target, tt.body, tt.cookie)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestHighlightCRUD(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+	bob := signUp(t, mux, "bob")
+
+	rec := serve(mux, http.MethodPost, "/api/highlights", testHighlight("h-1"), ann)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", rec.Code, rec.Body)
+	}
+	var created Highlight
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("create: decoding body: %v", err)
+	}
+	if created.ID != "h-1" || created.Color != "#ffff00" || created.CreatedAt == "" || created.OSISRef != "Gen.1.1" {
+		t.Errorf("create returned %+v", created)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		cookie *http.Cookie
+		want   int
+		check  func(t *testing.T, body string)
+	}{
+		{"get", http.MethodGet, "/api/highlights/h-1", "", ann, http.StatusOK, func(t *testing.T, body string) {
+			if !strings.Contains(body, `"id":"h-1"`) {
+				t.Errorf("body %s does not contain the highlight", body)
+			}
+		}},
+		{"list chapter", http.MethodGet, "/api/highlights?translation=KJV&bookId=1&chapter=1", "", ann, http.StatusOK, func(t *testing.T, body string) {
+			if !strings.Contains(body, `"id":"h-1"`) {
+				t.Errorf("body %s does not list the highlight", body)
+			}
+		}},
+		{"list other chapter", http.MethodGet, "/api/highlights?translation=KJV&bookId=1&chapter=2", "", ann, http.StatusOK, func(t *testing.T, body string) {
+			if strings.Contains(body, `"id":"h-1"`) {
+				t.Errorf("body %s lists a highlight from another chapter", body)
+			}
+		}},
+		{"list without chapter query", http.MethodGet, "/api/highlights?translation=KJV", "", ann, http.StatusBadRequest, nil},
+		{"get as another user", http.MethodGet, "/api/highlights/h-1", "", bob, http.StatusNotFound, nil},
+		{"create duplicate ID", http.MethodPost, "/api/highlights", testHighlight("h-1"), ann, http.StatusConflict, nil},
+		{"create invalid type", http.MethodPost, "/api/highlights", strings.Replace(testHighlight("h-2"), "highlight-only", "glitter", 1), ann, http.StatusUnprocessableEntity, nil},
+		{"create invalid color", http.MethodPost, "/api/highlights", strings.Replace(testHighlight("h-2"), "#ffff00", "not a color", 1), ann, http.StatusUnprocessableEntity, nil},
+		{"create malformed body", http.MethodPost, "/api/highlights", `{"id":`, ann, http.StatusBadRequest, nil},
+		{"update note", http.MethodPut, "/api/highlights/h-1", `{"note":"In the beginning"}`, ann, http.StatusOK, func(t *testing.T, body string) {
+			if !strings.Contains(body, `"note":"In the beginning"`) {
+				t.Errorf("body %s does not have the new note", body)
+			}
+		}},
+		{"update invalid type", http.MethodPut, "/api/highlights/h-1", `{"type":"glitter"}`, ann, http.StatusUnprocessableEntity, nil},
+		{"update missing", http.MethodPut, "/api/highlights/h-404", `{"note":"x"}`, ann, http.StatusNotFound, nil},
+		{"update as another user", http.MethodPut, "/api/highlights/h-1", `{"note":"mine now"}`, bob, http.StatusNotFound, nil},
+		{"patch not allowed", http.MethodPatch, "/api/highlights/h-1", `{}`, ann, http.StatusMethodNotAllowed, nil},
+		{"unknown API path", http.MethodGet, "/api/nothing_here", "", ann, http.StatusNotFound, nil},
+		{"delete as another user", http.MethodDelete, "/api/highlights/delete/h-1", "", bob, http.StatusNotFound, nil},
+		{"delete", http.MethodDelete, "/api/highlights/delete/h-1", "", ann, http.StatusOK, nil},
+		{"get after delete", http.MethodGet, "/api/highlights/h-1", "", ann, http.StatusNotFound, nil},
+		{"delete again", http.MethodDelete, "/api/highlights/delete/h-1", "", ann, http.StatusNotFound, nil},
+	}
+	// The cases build on each other, so they run in order against one database.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(mux, tt.method, tt.target, tt.body, tt.cookie)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.check != nil {
+				tt.check(t, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestBulkCreateIsAllOrNothing(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+
+	body := `[` + testHighlight("b-1") + `,` + strings.Replace(testHighlight("b-2"), "highlight-only", "glitter", 1) + `]`
+	if rec := serve(mux, http.MethodPost, "/api/highlights/bulk", body, ann); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("bulk with an invalid item: status %d, want 422: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(mux, http.MethodGet, "/api/highlights/b-1", "", ann); rec.Code != http.StatusNotFound {
+		t.Errorf("valid item of a rejected batch was stored: status %d", rec.Code)
+	}
+}
+
+func TestDeleteChapterClearsSpans(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+
+	// Genesis 1:31-2:3 is stored under chapter 1 but shows in chapter 2.
+	span := strings.Replace(testHighlight("span"), `"verseId":"verse-1-1-1"`, `"verseId":"verse-1-1-31","endVerseId":"verse-1-2-3"`, 1)
+	other := strings.NewReplacer(`"verse-1-1-1"`, `"verse-1-3-1"`, `"chapter":1`, `"chapter":3`).Replace(testHighlight("other"))
+	for _, body := range []string{span, other} {
+		if rec := serve(mux, http.MethodPost, "/api/highlights", body, ann); rec.Code != http.StatusCreated {
+			t.Fatalf("create: status %d: %s", rec.Code, rec.Body)
+		}
+	}
+
+	// A page showing chapter 1 still shows the span, so it must hear that
+	// the span is gone. ann is the only user, so ann's ID is 1.
+	watcher := hub.register(chapterKey{1, "KJV", 1, 1})
+	defer hub.unregister(watcher)
+
+	rec := serve(mux, http.MethodDelete, "/api/highlights?translation=KJV&bookId=1&chapter=2", "", ann)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"deleted":1`) {
+		t.Fatalf("delete chapter 2: status %d: %s", rec.Code, rec.Body)
+	}
+	select {
+	case ev := <-watcher.send:
+		if ev.Type != "deleted" || ev.ID != "span" {
+			t.Errorf("chapter 1 got %+v, want the span deleted", ev)
+		}
+	default:
+		t.Error("chapter 1 was not told the span was deleted")
+	}
+	if rec := serve(mux, http.MethodGet, "/api/highlights/span", "", ann); rec.Code != http.StatusNotFound {
+		t.Errorf("span into the cleared chapter survived: status %d", rec.Code)
+	}
+	if rec := serve(mux, http.MethodGet, "/api/highlights/other", "", ann); rec.Code != http.StatusOK {
+		t.Errorf("highlight in another chapter was cleared: status %d", rec.Code)
+	}
+}
+
+func TestChapterQueryRejectsNonNumbers(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+
+	tests := []struct {
+		name   string
+		method string
+		query  string
+	}{
+		{"bookId word", http.MethodGet, "translation=KJV&bookId=genesis&chapter=1"},
+		{"bookId injection", http.MethodGet, "translation=KJV&bookId=1%20OR%201=1&chapter=1"},
+		{"bookId zero", http.MethodGet, "translation=KJV&bookId=0&chapter=1"},
+		{"chapter word", http.MethodGet, "translation=KJV&bookId=1&chapter=one"},
+		{"chapter float", http.MethodGet, "translation=KJV&bookId=1&chapter=1.5"},
+		{"chapter negative", http.MethodGet, "translation=KJV&bookId=1&chapter=-1"},
+		{"delete bookId injection", http.MethodDelete, "translation=KJV&bookId=1%20OR%201=1&chapter=1"},
+		{"delete chapter word", http.MethodDelete, "translation=KJV&bookId=1&chapter=one"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(mux, tt.method, "/api/highlights?"+tt.query, "", ann)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400: %s", rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestWholeBookHighlightsAreInReadingOrder(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+
+	// Created out of order; verse 10 must sort after verse 2, not as text.
+	for _, h := range []struct {
+		id           string
+		chapter, vrs int
+		start        string
+	}{
+		{"c3", 3, 1, "0"},
+		{"c1v10", 1, 10, "0"},
+		{"c1v2-late", 1, 2, "8"},
+		{"c2", 2, 1, "0"},
+		{"c1v2-early", 1, 2, "1"},
+	} {
+		body := strings.NewReplacer(
+			`"verse-1-1-1"`, fmt.Sprintf(`"verse-1-%d-%d"`, h.chapter, h.vrs),
+			`"chapter":1`, fmt.Sprintf(`"chapter":%d`, h.chapter),
+			`"start":0,"end":5`, `"start":`+h.start+`,"end":12`,
+		).Replace(testHighlight(h.id))
+		if rec := serve(mux, http.MethodPost, "/api/highlights", body, ann); rec.Code != http.StatusCreated {
+			t.Fatalf("create %s: status %d: %s", h.id, rec.Code, rec.Body)
+		}
+	}
+
+	rec := serve(mux, http.MethodGet, "/api/highlights?translation=KJV&bookId=1", "", ann)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var got []Highlight
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decoding body: %v", err)
+	}
+	var ids []string
+	for _, h := range got {
+		ids = append(ids, h.ID)
+	}
+	want := []string{"c1v2-early", "c1v2-late", "c1v10", "c2", "c3"}
+	if !slices.Equal(ids, want) {
+		t.Errorf("order = %v, want %v", ids, want)
+	}
+}
+
+func TestHandlerGivesUpWhenRequestIsCancelled(t *testing.T) {
+	useTestDB(t)
+
+	// The in-memory database has a single connection. Holding it leaves the
+	// handler's query waiting, which only the request context can end.
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), userIDKey{}, int64(1)))
+	req := httptest.NewRequest(http.MethodGet, "/api/highlights?translation=KJV&bookId=1&chapter=1", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		getHighlightsHandler(rec, req)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler still running a second after the request was cancelled")
+	}
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500 for the abandoned query", rec.Code)
+	}
+}
+
+func TestMarkdownIncludesSpansFromEarlierChapters(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+
+	span := strings.NewReplacer(
+		`"verseId":"verse-1-1-1"`, `"verseId":"verse-1-1-31","endVerseId":"verse-1-2-3"`,
+		`"type":"highlight-only"`, `"type":"note","note":"Creation finished"`,
+	).Replace(testHighlight("span"))
+	if rec := serve(mux, http.MethodPost, "/api/highlights", span, ann); rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := serve(mux, http.MethodGet, "/api/highlights/markdown?translation=KJV&bookId=1&chapter=2", "", ann)
+	if want := "## Genesis 1:31\n\nCreation finished\n"; rec.Code != http.StatusOK || rec.Body.String() != want {
+		t.Errorf("got %d %q, want %q", rec.Code, rec.Body, want)
+	}
+}
+
+func TestHighlightedVersesCoverSpans(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+
+	// Genesis 1:30-2:2 and 2:24-3:1; Genesis 2 has 25 verses.
+	for _, h := range []struct{ id, start, end string }{
+		{"into-2", "verse-1-1-30", "verse-1-2-2"},
+		{"out-of-2", "verse-1-2-24", "verse-1-3-1"},
+		{"single", "verse-1-2-10", ""},
+	} {
+		_, chapter, _, _ := parseVerseID(h.start)
+		body := strings.NewReplacer(
+			`"verseId":"verse-1-1-1"`, `"verseId":"`+h.start+`","endVerseId":"`+h.end+`"`,
+			`"chapter":1`, fmt.Sprintf(`"chapter":%d`, chapter),
+		).Replace(testHighlight(h.id))
+		if rec := serve(mux, http.MethodPost, "/api/highlights", body, ann); rec.Code != http.StatusCreated {
+			t.Fatalf("create %s: status %d: %s", h.id, rec.Code, rec.Body)
+		}
+	}
+
+	tests := []struct {
+		chapter string
+		want    []string
+	}{
+		{"1", []string{"verse-1-1-30", "verse-1-1-31"}},
+		{"2", []string{"verse-1-2-1", "verse-1-2-2", "verse-1-2-10", "verse-1-2-24", "verse-1-2-25"}},
+		{"3", []string{"verse-1-3-1"}},
+		{"4", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run("chapter "+tt.chapter, func(t *testing.T) {
+			rec := serve(mux, http.MethodGet, "/api/highlights/verses?translation=KJV&bookId=1&chapter="+tt.chapter, "", ann)
+			var got []string
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("status %d: decoding body: %v", rec.Code, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("verses = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchHighlightsInVerseOrder(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	mux.HandleFunc("/api/highlights/search", requireUser(searchHighlightsHandler))
+	ann := signUp(t, mux, "ann")
+
+	// Created out of order, and with verse 10 sorting before verse 9 as text.
+	for _, verse := range []string{"10", "9", "1"} {
+		body := strings.NewReplacer(
+			`"verseId":"verse-1-1-1"`, `"verseId":"verse-1-1-`+verse+`","note":"light"`,
+		).Replace(testHighlight("h-" + verse))
+		if rec := serve(mux, http.MethodPost, "/api/highlights", body, ann); rec.Code != http.StatusCreated {
+			t.Fatalf("create verse %s: status %d: %s", verse, rec.Code, rec.Body)
+		}
+	}
+
+	rec := serve(mux, http.MethodGet, "/api/highlights/search?q=light", "", ann)
+	var got []Highlight
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("status %d: decoding body: %v", rec.Code, err)
+	}
+	var ids []string
+	for _, h := range got {
+		ids = append(ids, h.ID)
+	}
+	if want := []string{"h-1", "h-9", "h-10"}; !slices.Equal(ids, want) {
+		t.Errorf("results = %v, want %v", ids, want)
+	}
+}
+
+func TestHighlightValidate(t *testing.T) {
+	valid := Highlight{ID: "h-1", Type: "note", VerseID: "verse-43-3-16", Start: 2, End: 9, Translation: "KJV", BookID: 43, Chapter: 3, Color: " #FFFF00 "}
+
+	tests := []struct {
+		name   string
+		modify func(h *Highlight)
+		want   string // substring of the error; empty for a valid highlight
+	}{
+		{"valid", func(h *Highlight) {}, ""},
+		{"empty range", func(h *Highlight) { h.Start, h.End = 4, 4 }, ""},
+		{"missing id", func(h *Highlight) { h.ID = "" }, "id is required"},
+		{"id with slash", func(h *Highlight) { h.ID = "a/b" }, "contain no slashes"},
+		{"id too long", func(h *Highlight) { h.ID = strings.Repeat("x", maxHighlightIDLength+1) }, "at most"},
+		{"missing verseId", func(h *Highlight) { h.VerseID = "" }, "verseId is required"},
+		{"missing translation", func(h *Highlight) { h.Translation = "" }, "translation is required"},
+		{"unknown type", func(h *Highlight) { h.Type = "glitter" }, "glitter"},
+		{"negative start", func(h *Highlight) { h.Start = -1 }, "start must not be negative"},
+		{"end before start", func(h *Highlight) { h.Start, h.End = 9, 2 }, "end must not be before start"},
+		{"bookId zero", func(h *Highlight) { h.BookID = 0 }, "bookId must be between 1 and 66"},
+		{"bookId past Revelation", func(h *Highlight) { h.BookID = 67 }, "bookId must be between 1 and 66"},
+		{"chapter zero", func(h *Highlight) { h.Chapter = 0 }, "chapter must be a positive integer"},
+		{"span", func(h *Highlight) { h.EndVerseID = "verse-43-3-17" }, ""},
+		{"span backwards", func(h *Highlight) { h.EndVerseID = "verse-43-3-15" }, "must not come before"},
+		{"span into another book", func(h *Highlight) { h.EndVerseID = "verse-44-1-1" }, "same book"},
+		{"malformed endVerseId", func(h *Highlight) { h.EndVerseID = "John 3:17" }, "Invalid endVerseId"},
+		{"bad color", func(h *Highlight) { h.Color = "not a color" }, "Invalid color"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := valid
+			tt.modify(&h)
+			err := h.validate()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("validate() = %v, want nil", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("validate() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+
+	h := valid
+	if err := h.validate(); err != nil || h.Color != "#ffff00" {
+		t.Errorf("valid highlight: err %v, color %q, want nil and #ffff00", err, h.Color)
+	}
+}
+
+func TestDeleteRejectsAmbiguousIDs(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+	for _, id := range []string{"h-1", "a b"} {
+		if rec := serve(mux, http.MethodPost, "/api/highlights", testHighlight(id), ann); rec.Code != http.StatusCreated {
+			t.Fatalf("create %q: status %d: %s", id, rec.Code, rec.Body)
+		}
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"encoded slash", "/api/highlights/delete/h-1%2Fx", http.StatusBadRequest},
+		{"encoded slash alone", "/api/highlights/delete/%2F", http.StatusBadRequest},
+		{"trailing segment", "/api/highlights/delete/h-1/extra", http.StatusBadRequest},
+		{"overly long", "/api/highlights/delete/" + strings.Repeat("h", maxHighlightIDLength+1), http.StatusBadRequest},
+		{"missing", "/api/highlights/delete/", http.StatusBadRequest},
+		{"encoded space is decoded", "/api/highlights/delete/a%20b", http.StatusOK},
+		{"plain", "/api/highlights/delete/h-1", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(mux, http.MethodDelete, tt.path, "", ann)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestOversizedBodiesAreRejected(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+	if rec := serve(mux, http.MethodPost, "/api/highlights", testHighlight("h-1"), ann); rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", rec.Code, rec.Body)
+	}
+
+	withNote := func(id string, size int) string {
+		return strings.Replace(testHighlight(id), `"type"`, `"note":"`+strings.Repeat("x", size)+`","type"`, 1)
+	}
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+	}{
+		{"create", http.MethodPost, "/api/highlights", withNote("big", maxHighlightBodyBytes)},
+		{"update", http.MethodPut, "/api/highlights/h-1", `{"note":"` + strings.Repeat("x", maxHighlightBodyBytes) + `"}`},
+		{"import", http.MethodPost, "/api/highlights/import", `[` + withNote("big", maxImportBodyBytes) + `]`},
+		{"palette", http.MethodPut, "/api/palette?translation=KJV", `{"note":"` + strings.Repeat("x", maxPaletteBodyBytes) + `"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(mux, tt.method, tt.target, tt.body, ann); rec.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("status = %d, want 413: %s", rec.Code, rec.Body)
+			}
+		})
+	}
+
+	if rec := serve(mux, http.MethodGet, "/api/highlights/big", "", ann); rec.Code != http.StatusNotFound {
+		t.Errorf("oversized highlight was stored: status %d", rec.Code)
+	}
+	if rec := serve(mux, http.MethodGet, "/api/highlights/h-1", "", ann); strings.Contains(rec.Body.String(), "xxx") {
+		t.Error("oversized update was applied")
+	}
+	// A note well within the limit is still accepted.
+	if rec := serve(mux, http.MethodPost, "/api/highlights", withNote("long", 64<<10), ann); rec.Code != http.StatusCreated {
+		t.Errorf("create with a long note: status %d: %s", rec.Code, rec.Body)
+	}
+}
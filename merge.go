@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+)
+
+// mergeOverlapping folds the user's existing highlights that overlap h into
+// h: those of the same type on the same single verse whose range intersects
+// it. h is widened to the union of the ranges, gains their tags and has their
+// notes prepended to its own, and the overlapped rows are moved to the trash and
+// returned so the caller can announce their removal. Spans are never merged.
+func mergeOverlapping(ctx context.Context, tx *sql.Tx, h *Highlight) ([]Highlight, error) {
+	if h.EndVerseID != "" && h.EndVerseID != h.VerseID {
+		return nil, nil
+	}
+
+	query := `SELECT ` + highlightColumns + ` FROM highlights
+	          WHERE userId = ? AND translation = ? AND verseId = ? AND type = ?
+	              AND (endVerseId = '' OR endVerseId = verseId)
+	              AND start < ? AND end > ? AND id != ? AND deletedAt IS NULL
+	          ORDER BY createdAt, rowid`
+	rows, err := tx.QueryContext(ctx, query, h.UserID, h.Translation, h.VerseID, h.Type, h.End, h.Start, h.ID)
+	if err != nil {
+		return nil, err
+	}
+	var merged []Highlight
+	for rows.Next() {
+		old, err := scanHighlight(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		old.UserID = h.UserID
+		merged = append(merged, old)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var notes []string
+	for _, old := range merged {
+		h.Start = min(h.Start, old.Start)
+		h.End = max(h.End, old.End)
+		h.Tags = append(h.Tags, old.Tags...)
+		if old.Note != "" && !slices.Contains(notes, old.Note) {
+			notes = append(notes, old.Note)
+		}
+		if _, err := tx.StmtContext(ctx, trashHighlightStmt).ExecContext(ctx, h.UpdatedAt, old.ID, h.UserID); err != nil {
+			return nil, err
+		}
+	}
+	if h.Note != "" && !slices.Contains(notes, h.Note) {
+		notes = append(notes, h.Note)
+	}
+	h.Note = strings.Join(notes, "\n\n")
+	h.Tags = cleanTags(h.Tags)
+	return merged, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+)
+
+// metadataJSON lists the supported translations and the books of the canon
+// with their chapter and verse counts. Book IDs and names match books.go.
+//
+//go:embed data/metadata.json
+var metadataJSON []byte
+
+// Metadata is the body of /api/metadata.
+type Metadata struct {
+	Translations []TranslationInfo `json:"translations"`
+	Books        []BookInfo        `json:"books"`
+}
+
+// TranslationInfo is a translation the app can display.
+type TranslationInfo struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// BookInfo describes one book of the canon.
+type BookInfo struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	OSIS      string `json:"osis"`
+	Testament string `json:"testament"`
+	Chapters  int    `json:"chapters"`
+	Verses    []int  `json:"verses"` // Verses in each chapter, in KJV versification
+}
+
+// metadata is decoded once at startup; a malformed data file is a build
+// mistake, so it panics rather than failing at request time.
+var metadata = func() Metadata {
+	var m Metadata
+	if err := json.Unmarshal(metadataJSON, &m); err != nil {
+		panic("data/metadata.json: " + err.Error())
+	}
+	return m
+}()
+
+// metadataHandler returns the translations and books the app supports, so
+// the frontend does not need its own copy.
+func metadataHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	json.NewEncoder(w).Encode(metadata)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the request duration
+// histogram: Prometheus's default buckets.
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	path   string
+	status int
+}
+
+// histogram counts observations at or below each of latencyBuckets.
+type histogram struct {
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+// appMetrics holds the counters served by /metrics. Requests are labelled by
+// the mux pattern that handled them rather than the raw path, so that IDs in
+// the path do not create a series each.
+type appMetrics struct {
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	latencies map[string]*histogram
+	scrapes   map[string]uint64 // Blue Letter Bible fetches by result
+}
+
+var metrics = &appMetrics{
+	requests:  map[requestKey]uint64{},
+	latencies: map[string]*histogram{},
+	scrapes:   map[string]uint64{"success": 0, "failure": 0},
+}
+
+func (m *appMetrics) observeRequest(path string, status int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{path, status}]++
+	h, ok := m.latencies[path]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(latencyBuckets))}
+		m.latencies[path] = h
+	}
+	seconds := d.Seconds()
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// observeScrape counts a Blue Letter Bible lookup as succeeding or failing.
+func (m *appMetrics) observeScrape(err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.mu.Lock()
+	m.scrapes[result]++
+	m.mu.Unlock()
+}
+
+// writeTo writes every metric in the Prometheus text exposition format.
+func (m *appMetrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP bible_http_requests_total Requests handled, by route and status code.")
+	fmt.Fprintln(w, "# TYPE bible_http_requests_total counter")
+	keys := slices.SortedFunc(maps.Keys(m.requests), func(a, b requestKey) int {
+		return cmp.Or(cmp.Compare(a.path, b.path), cmp.Compare(a.status, b.status))
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "bible_http_requests_total{path=%s,status=\"%d\"} %d\n", strconv.Quote(k.path), k.status, m.requests[k])
+	}
+
+	fmt.Fprintln(w, "# HELP bible_http_request_duration_seconds Time taken to handle requests, by route.")
+	fmt.Fprintln(w, "# TYPE bible_http_request_duration_seconds histogram")
+	for _, path := range slices.Sorted(maps.Keys(m.latencies)) {
+		h := m.latencies[path]
+		label := strconv.Quote(path)
+		for i, bound := range latencyBuckets {
+			fmt.Fprintf(w, "bible_http_request_duration_seconds_bucket{path=%s,le=\"%g\"} %d\n", label, bound, h.buckets[i])
+		}
+		fmt.Fprintf(w, "bible_http_request_duration_seconds_bucket{path=%s,le=\"+Inf\"} %d\n", label, h.count)
+		fmt.Fprintf(w, "bible_http_request_duration_seconds_sum{path=%s} %g\n", label, h.sum)
+		fmt.Fprintf(w, "bible_http_request_duration_seconds_count{path=%s} %d\n", label, h.count)
+	}
+
+	fmt.Fprintln(w, "# HELP bible_blb_scrapes_total Blue Letter Bible lookups, by whether they succeeded.")
+	fmt.Fprintln(w, "# TYPE bible_blb_scrapes_total counter")
+	for _, result := range slices.Sorted(maps.Keys(m.scrapes)) {
+		fmt.Fprintf(w, "bible_blb_scrapes_total{result=%q} %d\n", result, m.scrapes[result])
+	}
+}
+
+// metricsMiddleware records the status and duration of every request handled
+// by next. It relies on next being the mux, which sets r.Pattern.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		path := r.Pattern
+		if path == "" {
+			path = "unmatched"
+		}
+		metrics.observeRequest(path, rec.status, time.Since(start))
+	})
+}
+
+// metricsHandler serves the metrics for Prometheus to scrape.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	metrics.writeTo(w)
+}
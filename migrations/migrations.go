@@ -0,0 +1,503 @@
+// Package migrations brings the SQLite schema up to date. Each migration runs
+// once, in order, and is recorded in the schema_migrations table, so columns
+// and tables can be added over time without re-running earlier steps on an
+// existing database.
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Migration is one schema change. Exactly one of SQL and Func is set; Func is
+// for changes that need to inspect the database first.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+	Func    func(tx *sql.Tx) error
+}
+
+// All lists every migration in the order it is applied. Append new ones at
+// the end with the next version number; never edit or reorder applied ones.
+//
+// Databases created before migrations existed already have some of these
+// tables and columns, which is why creates use IF NOT EXISTS and columns are
+// added with addColumn.
+var All = []Migration{
+	{
+		Version: 1,
+		Name:    "create_highlights",
+		SQL: `CREATE TABLE IF NOT EXISTS highlights (
+				"id" TEXT NOT NULL PRIMARY KEY,
+				"type" TEXT NOT NULL,
+				"verseId" TEXT NOT NULL,
+				"start" INTEGER NOT NULL,
+				"end" INTEGER NOT NULL,
+				"note" TEXT,
+				"translation" TEXT NOT NULL,
+				"bookId" INTEGER NOT NULL,
+				"chapter" INTEGER NOT NULL
+			);`,
+	},
+	{
+		Version: 2,
+		Name:    "add_highlights_color",
+		Func: func(tx *sql.Tx) error {
+			return addColumn(tx, "highlights", "color", `TEXT NOT NULL DEFAULT ''`)
+		},
+	},
+	{
+		// Highlights saved before accounts existed have userId 0 until the
+		// first account is registered and adopts them.
+		Version: 3,
+		Name:    "add_highlights_user",
+		Func: func(tx *sql.Tx) error {
+			return addColumn(tx, "highlights", "userId", `INTEGER NOT NULL DEFAULT 0`)
+		},
+	},
+	{
+		// Highlights are always looked up by these columns. The index leads
+		// with userId, replacing the earlier one without it.
+		Version: 4,
+		Name:    "index_highlights_user_lookup",
+		SQL: `DROP INDEX IF EXISTS idx_highlights_lookup;
+			CREATE INDEX IF NOT EXISTS idx_highlights_user_lookup ON highlights(userId, translation, bookId, chapter);`,
+	},
+	{
+		// Session expiry is stored as a Unix timestamp so it can be compared
+		// directly in SQL.
+		Version: 5,
+		Name:    "create_users",
+		SQL: `CREATE TABLE IF NOT EXISTS users (
+				"id" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+				"username" TEXT NOT NULL UNIQUE,
+				"passwordHash" TEXT NOT NULL
+			);
+			CREATE TABLE IF NOT EXISTS sessions (
+				"token" TEXT NOT NULL PRIMARY KEY,
+				"userId" INTEGER NOT NULL,
+				"expiresAt" INTEGER NOT NULL
+			);`,
+	},
+	{
+		// strongs_cache holds one row per Strong's number, and strongs_lookups
+		// remembers which number a word in a given verse resolved to, so a repeat
+		// lookup can be answered without knowing the number up front.
+		Version: 6,
+		Name:    "create_strongs_cache",
+		SQL: `CREATE TABLE IF NOT EXISTS strongs_cache (
+				"strongsNumber" TEXT NOT NULL PRIMARY KEY,
+				"lexeme" TEXT NOT NULL,
+				"transliteration" TEXT NOT NULL,
+				"definition" TEXT NOT NULL
+			);
+			CREATE TABLE IF NOT EXISTS strongs_lookups (
+				"word" TEXT NOT NULL,
+				"translation" TEXT NOT NULL,
+				"bookName" TEXT NOT NULL,
+				"chapter" TEXT NOT NULL,
+				"verse" TEXT NOT NULL,
+				"strongsNumber" TEXT NOT NULL,
+				PRIMARY KEY ("word", "translation", "bookName", "chapter", "verse")
+			);`,
+	},
+	{
+		// Local copy of chapter text fetched from bolls.life.
+		Version: 7,
+		Name:    "create_verses",
+		SQL: `CREATE TABLE IF NOT EXISTS verses (
+				"translation" TEXT NOT NULL,
+				"bookId" INTEGER NOT NULL,
+				"chapter" INTEGER NOT NULL,
+				"verse" INTEGER NOT NULL,
+				"text" TEXT NOT NULL,
+				PRIMARY KEY ("translation", "bookId", "chapter", "verse")
+			);`,
+	},
+	{
+		// A reference to a passage rather than a single verse has its last verse
+		// in endChapter/endVerse.
+		Version: 8,
+		Name:    "create_cross_references",
+		SQL: `CREATE TABLE IF NOT EXISTS cross_references (
+				"fromBookId" INTEGER NOT NULL,
+				"fromChapter" INTEGER NOT NULL,
+				"fromVerse" INTEGER NOT NULL,
+				"toBookId" INTEGER NOT NULL,
+				"toChapter" INTEGER NOT NULL,
+				"toVerse" INTEGER NOT NULL,
+				"endChapter" INTEGER,
+				"endVerse" INTEGER,
+				"votes" INTEGER NOT NULL DEFAULT 0
+			);
+			CREATE INDEX IF NOT EXISTS idx_cross_references_from ON cross_references(fromBookId, fromChapter, fromVerse);`,
+	},
+	{
+		// One row per user, overwritten on every save.
+		Version: 9,
+		Name:    "create_reading_progress",
+		SQL: `CREATE TABLE IF NOT EXISTS reading_progress (
+				"userId" INTEGER NOT NULL PRIMARY KEY,
+				"translation" TEXT NOT NULL,
+				"bookId" INTEGER NOT NULL,
+				"chapter" INTEGER NOT NULL,
+				"verse" INTEGER NOT NULL DEFAULT 1,
+				"updatedAt" TEXT NOT NULL
+			);`,
+	},
+	{
+		// Deleting a highlight cascades to its tag links; this relies on foreign
+		// keys being enabled in the connection DSN.
+		Version: 10,
+		Name:    "create_tags",
+		SQL: `CREATE TABLE IF NOT EXISTS tags (
+				"id" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+				"userId" INTEGER NOT NULL,
+				"name" TEXT NOT NULL COLLATE NOCASE,
+				UNIQUE ("userId", "name")
+			);
+			CREATE TABLE IF NOT EXISTS highlight_tags (
+				"highlightId" TEXT NOT NULL REFERENCES highlights(id) ON DELETE CASCADE,
+				"tagId" INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+				PRIMARY KEY ("highlightId", "tagId")
+			);
+			CREATE INDEX IF NOT EXISTS idx_highlight_tags_tag ON highlight_tags(tagId);`,
+	},
+	{
+		// SQLite cannot add a column with a CURRENT_TIMESTAMP default, so
+		// existing rows are backfilled with the time of the migration and the
+		// handlers set both columns on every write.
+		Version: 11,
+		Name:    "add_highlights_timestamps",
+		Func: func(tx *sql.Tx) error {
+			if err := addColumn(tx, "highlights", "createdAt", `TEXT NOT NULL DEFAULT ''`); err != nil {
+				return err
+			}
+			if err := addColumn(tx, "highlights", "updatedAt", `TEXT NOT NULL DEFAULT ''`); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`UPDATE highlights SET createdAt = ` + strftimeNow + ` WHERE createdAt = '';
+				UPDATE highlights SET updatedAt = createdAt WHERE updatedAt = '';`)
+			return err
+		},
+	},
+	{
+		// Whole-chapter bookmarks, separate from verse-range highlights.
+		Version: 12,
+		Name:    "create_bookmarks",
+		SQL: `CREATE TABLE IF NOT EXISTS bookmarks (
+				"id" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+				"userId" INTEGER NOT NULL,
+				"translation" TEXT NOT NULL,
+				"bookId" INTEGER NOT NULL,
+				"chapter" INTEGER NOT NULL,
+				"label" TEXT NOT NULL DEFAULT '',
+				"createdAt" TEXT NOT NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_bookmarks_user ON bookmarks(userId, createdAt);`,
+	},
+	{
+		Version: 13,
+		Name:    "add_highlights_strongs_number",
+		Func: func(tx *sql.Tx) error {
+			return addColumn(tx, "highlights", "strongsNumber", `TEXT NOT NULL DEFAULT ''`)
+		},
+	},
+	{
+		// endChapter mirrors the chapter of endVerseId, so a chapter query
+		// can find spans that started in an earlier chapter with a range
+		// comparison. Single-verse highlights end in their own chapter.
+		Version: 14,
+		Name:    "add_highlights_verse_spans",
+		Func: func(tx *sql.Tx) error {
+			if err := addColumn(tx, "highlights", "endVerseId", `TEXT NOT NULL DEFAULT ''`); err != nil {
+				return err
+			}
+			if err := addColumn(tx, "highlights", "endChapter", `INTEGER NOT NULL DEFAULT 0`); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`UPDATE highlights SET endChapter = chapter WHERE endChapter = 0`)
+			return err
+		},
+	},
+	{
+		// Set on highlights copied from another translation, whose offsets
+		// could not be carried over.
+		Version: 15,
+		Name:    "add_highlights_needs_review",
+		Func: func(tx *sql.Tx) error {
+			return addColumn(tx, "highlights", "needsReview", `INTEGER NOT NULL DEFAULT 0`)
+		},
+	},
+	{
+		// The original-language text of each verse, one row per word, with
+		// language telling Hebrew apart from Greek (including the Septuagint).
+		Version: 16,
+		Name:    "create_original_words",
+		SQL: `CREATE TABLE IF NOT EXISTS original_words (
+				"bookId" INTEGER NOT NULL,
+				"chapter" INTEGER NOT NULL,
+				"verse" INTEGER NOT NULL,
+				"position" INTEGER NOT NULL,
+				"language" TEXT NOT NULL,
+				"word" TEXT NOT NULL,
+				"strongsNumber" TEXT NOT NULL DEFAULT '',
+				PRIMARY KEY ("bookId", "chapter", "verse", "position")
+			);`,
+	},
+	{
+		// colors is a JSON object mapping highlight type to a #rrggbb color.
+		Version: 17,
+		Name:    "create_palettes",
+		SQL: `CREATE TABLE IF NOT EXISTS palettes (
+				"userId" INTEGER NOT NULL,
+				"translation" TEXT NOT NULL,
+				"colors" TEXT NOT NULL,
+				"updatedAt" TEXT NOT NULL,
+				PRIMARY KEY ("userId", "translation")
+			);`,
+	},
+	{
+		// A deleted highlight keeps its row, with deletedAt set, until it is
+		// restored or purged from the trash.
+		Version: 18,
+		Name:    "add_highlights_deleted_at",
+		Func: func(tx *sql.Tx) error {
+			if err := addColumn(tx, "highlights", "deletedAt", `TEXT`); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_highlights_deleted ON highlights(deletedAt)`)
+			return err
+		},
+	},
+	{
+		// A share is a frozen copy of a chapter's highlights, kept as the
+		// JSON array in highlights, that anyone holding the token can view.
+		Version: 19,
+		Name:    "create_shares",
+		SQL: `CREATE TABLE IF NOT EXISTS shares (
+				"token" TEXT NOT NULL PRIMARY KEY,
+				"userId" INTEGER NOT NULL,
+				"translation" TEXT NOT NULL,
+				"bookId" INTEGER NOT NULL,
+				"chapter" INTEGER NOT NULL,
+				"highlights" TEXT NOT NULL,
+				"createdAt" TEXT NOT NULL
+			);`,
+	},
+	{
+		// Each row is the note a highlight had before an update replaced it.
+		Version: 20,
+		Name:    "create_highlight_note_history",
+		SQL: `CREATE TABLE IF NOT EXISTS highlight_note_history (
+				"id" INTEGER PRIMARY KEY AUTOINCREMENT,
+				"highlightId" TEXT NOT NULL,
+				"note" TEXT NOT NULL,
+				"replacedAt" TEXT NOT NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_highlight_note_history_highlight ON highlight_note_history(highlightId);`,
+	},
+	{
+		// osisRef names the first verse of a highlight the same way in every
+		// translation, e.g. "John.3.16". Existing rows are backfilled from
+		// verseId, whose verse is whatever follows "verse-{bookId}-{chapter}-";
+		// rows whose verseId has some other form are left with "".
+		Version: 21,
+		Name:    "add_highlights_osis_ref",
+		Func: func(tx *sql.Tx) error {
+			if err := addColumn(tx, "highlights", "osisRef", `TEXT NOT NULL DEFAULT ''`); err != nil {
+				return err
+			}
+			const osisBooks = `[
+				"Gen", "Exod", "Lev", "Num", "Deut", "Josh", "Judg", "Ruth", "1Sam", "2Sam",
+				"1Kgs", "2Kgs", "1Chr", "2Chr", "Ezra", "Neh", "Esth", "Job", "Ps", "Prov",
+				"Eccl", "Song", "Isa", "Jer", "Lam", "Ezek", "Dan", "Hos", "Joel", "Amos",
+				"Obad", "Jonah", "Mic", "Nah", "Hab", "Zeph", "Hag", "Zech", "Mal",
+				"Matt", "Mark", "Luke", "John", "Acts", "Rom", "1Cor", "2Cor", "Gal", "Eph",
+				"Phil", "Col", "1Thess", "2Thess", "1Tim", "2Tim", "Titus", "Phlm", "Heb", "Jas",
+				"1Pet", "2Pet", "1John", "2John", "3John", "Jude", "Rev"
+			]`
+			_, err := tx.Exec(`UPDATE highlights SET osisRef = json_extract(?, '$[' || (bookId - 1) || ']') || '.' || chapter || '.' ||
+			                       CAST(substr(verseId, length('verse-' || bookId || '-' || chapter || '-') + 1) AS INTEGER)
+			                   WHERE osisRef = '' AND bookId BETWEEN 1 AND 66
+			                       AND verseId LIKE 'verse-' || bookId || '-' || chapter || '-%'`, osisBooks)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS idx_highlights_user_osis ON highlights(userId, osisRef)`)
+			return err
+		},
+	},
+	{
+		// Existing cache entries are left with "" and have their HTML made
+		// from the plain-text definition when read.
+		Version: 22,
+		Name:    "add_strongs_cache_definition_html",
+		Func: func(tx *sql.Tx) error {
+			return addColumn(tx, "strongs_cache", "definitionHtml", `TEXT NOT NULL DEFAULT ''`)
+		},
+	},
+	{
+		// The response sent for each Idempotency-Key a user has created a
+		// highlight with, replayed if the request is repeated.
+		Version: 23,
+		Name:    "create_idempotency_keys",
+		SQL: `CREATE TABLE IF NOT EXISTS idempotency_keys (
+				"userId" INTEGER NOT NULL,
+				"key" TEXT NOT NULL,
+				"response" BLOB NOT NULL,
+				"createdAt" TEXT NOT NULL,
+				PRIMARY KEY ("userId", "key")
+			);
+			CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys(createdAt);`,
+	},
+	{
+		// Named lists of verses, independent of highlights and translations.
+		// Like highlight_tags, deleting a collection cascades to its items.
+		Version: 24,
+		Name:    "create_collections",
+		SQL: `CREATE TABLE IF NOT EXISTS collections (
+				"id" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+				"userId" INTEGER NOT NULL,
+				"name" TEXT NOT NULL COLLATE NOCASE,
+				"description" TEXT NOT NULL DEFAULT '',
+				"createdAt" TEXT NOT NULL,
+				"updatedAt" TEXT NOT NULL,
+				UNIQUE ("userId", "name")
+			);
+			CREATE TABLE IF NOT EXISTS collection_items (
+				"id" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+				"collectionId" INTEGER NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+				"bookId" INTEGER NOT NULL,
+				"chapter" INTEGER NOT NULL,
+				"verse" INTEGER NOT NULL,
+				"addedAt" TEXT NOT NULL,
+				UNIQUE ("collectionId", "bookId", "chapter", "verse")
+			);`,
+	},
+	{
+		// The verse text as read, without the markup bolls.life includes, for
+		// the text search to index. Removing the markup takes an HTML parser,
+		// so the server fills it in when storing verses and backfills
+		// existing rows at startup.
+		Version: 25,
+		Name:    "add_verses_plain_text",
+		Func: func(tx *sql.Tx) error {
+			return addColumn(tx, "verses", "plainText", `TEXT NOT NULL DEFAULT ''`)
+		},
+	},
+	{
+		// Highlight IDs are chosen by clients and can be taken again once a
+		// highlight is purged, so history is keyed by its owner as well.
+		// History left behind by highlights that no longer exist is dropped
+		// rather than guessed at.
+		Version: 26,
+		Name:    "add_highlight_note_history_user_id",
+		Func: func(tx *sql.Tx) error {
+			if err := addColumn(tx, "highlight_note_history", "userId", `INTEGER NOT NULL DEFAULT 0`); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`DELETE FROM highlight_note_history WHERE highlightId NOT IN (SELECT id FROM highlights)`); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`UPDATE highlight_note_history SET userId = (SELECT userId FROM highlights WHERE id = highlightId)`)
+			return err
+		},
+	},
+}
+
+// strftimeNow is an SQL expression for the current UTC time in RFC3339 form,
+// matching the timestamps the handlers write.
+const strftimeNow = `strftime('%Y-%m-%dT%H:%M:%SZ', 'now')`
+
+// Apply runs every migration in All that has not been recorded yet, inside a
+// single transaction, and returns how many were applied. On error nothing is
+// applied.
+func Apply(db *sql.DB) (int, error) {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+			"version" INTEGER NOT NULL PRIMARY KEY,
+			"name" TEXT NOT NULL,
+			"appliedAt" TEXT NOT NULL
+		);`); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	applied := map[int]bool{}
+	rows, err := tx.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		applied[v] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range All {
+		if applied[m.Version] {
+			continue
+		}
+		if m.Func != nil {
+			err = m.Func(tx)
+		} else {
+			_, err = tx.Exec(m.SQL)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name, appliedAt) VALUES (?, ?, ?)`,
+			m.Version, m.Name, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// addColumn adds a column to an existing table unless it is already there.
+func addColumn(tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.Query(fmt.Sprintf(`PRAGMA table_info(%q)`, table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = tx.Exec(fmt.Sprintf(`ALTER TABLE %q ADD COLUMN %q %s`, table, column, definition))
+	return err
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// ChapterRef identifies a chapter of the canon.
+type ChapterRef struct {
+	BookID  int `json:"bookId"`
+	Chapter int `json:"chapter"`
+}
+
+// adjacentChapter returns the chapter before (step -1) or after (step 1) ref,
+// moving into the neighbouring book at a book boundary. ok is false past
+// either end of the Bible.
+func adjacentChapter(ref ChapterRef, step int) (ChapterRef, bool) {
+	next := ChapterRef{ref.BookID, ref.Chapter + step}
+	switch {
+	case next.Chapter < 1:
+		next.BookID--
+		if next.BookID < 1 {
+			return ChapterRef{}, false
+		}
+		next.Chapter = metadata.Books[next.BookID-1].Chapters
+	case next.Chapter > metadata.Books[ref.BookID-1].Chapters:
+		next.BookID++
+		if next.BookID > len(metadata.Books) {
+			return ChapterRef{}, false
+		}
+		next.Chapter = 1
+	}
+	return next, true
+}
+
+// navigateHandler resolves the previous or next chapter from bookId and
+// chapter, so the frontend does not need to know where books end.
+func navigateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var ref ChapterRef
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"bookId", &ref.BookID}, {"chapter", &ref.Chapter}} {
+		n, err := strconv.Atoi(r.URL.Query().Get(p.name))
+		if err != nil || n < 1 {
+			writeJSONError(w, http.StatusBadRequest, p.name+" must be a positive integer")
+			return
+		}
+		*p.dst = n
+	}
+	if ref.BookID > len(metadata.Books) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("bookId must be between 1 and %d", len(metadata.Books)))
+		return
+	}
+	if chapters := metadata.Books[ref.BookID-1].Chapters; ref.Chapter > chapters {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("%s has %d chapters", metadata.Books[ref.BookID-1].Name, chapters))
+		return
+	}
+
+	var step int
+	switch r.URL.Query().Get("direction") {
+	case "next":
+		step = 1
+	case "prev", "previous":
+		step = -1
+	default:
+		writeJSONError(w, http.StatusBadRequest, `direction must be "next" or "prev"`)
+		return
+	}
+
+	next, ok := adjacentChapter(ref, step)
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "No chapter in that direction")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(next)
+}
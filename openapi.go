@@ -0,0 +1,31 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+)
+
+// openAPISpec is the hand-written OpenAPI 3 description of the highlight and
+// Strong's endpoints. Update it alongside any change to their parameters or
+// the Highlight and StrongsDefinition structs.
+//
+//go:embed data/openapi.json
+var openAPISpec []byte
+
+func init() {
+	if !json.Valid(openAPISpec) {
+		panic("data/openapi.json is not valid JSON")
+	}
+}
+
+// openAPIHandler serves openAPISpec for client generators.
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
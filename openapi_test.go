@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestOpenAPIPathsAreRouted checks that every operation in the spec reaches a
+// handler that accepts its method, so the spec cannot document an endpoint
+// that was renamed or removed.
+func TestOpenAPIPathsAreRouted(t *testing.T) {
+	useTestDB(t)
+	useFetcher(t, fetcherFunc(func(ctx context.Context, url string) (*http.Response, error) {
+		return nil, errors.New("no network in tests")
+	}))
+	mux := newMux("", 0, nil)
+	cookie := signUp(t, mux, "ann")
+
+	type operation struct {
+		Parameters []struct {
+			Name     string `json:"name"`
+			In       string `json:"in"`
+			Required bool   `json:"required"`
+			Schema   struct {
+				Enum []string `json:"enum"`
+			} `json:"schema"`
+		} `json:"parameters"`
+	}
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("decoding spec: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") || len(spec.Paths) == 0 {
+		t.Fatalf("spec has openapi %q and %d paths", spec.OpenAPI, len(spec.Paths))
+	}
+
+	param := regexp.MustCompile(`\{[^}]+\}`)
+	methods := []string{http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete, http.MethodPatch}
+	for path, item := range spec.Paths {
+		for _, method := range methods {
+			raw, ok := item[strings.ToLower(method)]
+			if !ok {
+				continue
+			}
+			var op operation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				t.Fatalf("decoding %s %s: %v", method, path, err)
+			}
+			// Fill in required query parameters that only take fixed values,
+			// such as fix=clamp, since the handler checks those before
+			// anything else.
+			query := url.Values{}
+			for _, p := range op.Parameters {
+				if p.In == "query" && p.Required && len(p.Schema.Enum) > 0 {
+					query.Set(p.Name, p.Schema.Enum[0])
+				}
+			}
+			target := param.ReplaceAllString(path, "1")
+			if len(query) > 0 {
+				target += "?" + query.Encode()
+			}
+			t.Run(method+" "+path, func(t *testing.T) {
+				if _, pattern := mux.Handler(httptest.NewRequest(method, target, nil)); pattern == "/api/" || pattern == "/" {
+					t.Fatalf("routed to the catch-all %q", pattern)
+				}
+				rec := serve(mux, method, target, "", cookie)
+				if rec.Code == http.StatusMethodNotAllowed || strings.Contains(rec.Body.String(), `"Not found"`) {
+					t.Errorf("status %d: %s", rec.Code, rec.Body)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// OriginalVerse is a verse in its original language as returned by
+// /api/original, split into words tagged with Strong's numbers.
+type OriginalVerse struct {
+	BookID   int            `json:"bookId"`
+	Chapter  int            `json:"chapter"`
+	Verse    int            `json:"verse"`
+	Language string         `json:"language"`
+	Text     string         `json:"text"`
+	Words    []OriginalWord `json:"words"`
+}
+
+// OriginalWord is one word of an OriginalVerse.
+type OriginalWord struct {
+	Position      int    `json:"position"`
+	Word          string `json:"word"`
+	StrongsNumber string `json:"strongsNumber,omitempty"`
+}
+
+// Language codes stored with original-language words (ISO 639-3).
+const (
+	languageHebrew = "hbo"
+	languageGreek  = "grc"
+)
+
+// originalLanguage returns the language an interlinear row is in when the
+// file does not say: Hebrew for the Old Testament and Greek for the New.
+func originalLanguage(bookID int) string {
+	if bookID >= 1 && bookID <= len(metadata.Books) && metadata.Books[bookID-1].Testament == "NT" {
+		return languageGreek
+	}
+	return languageHebrew
+}
+
+// loadInterlinear seeds the original_words table from a tab-separated
+// interlinear file with a header row and one word per line: OSIS verse
+// reference, word position within the verse, the word, its Strong's number
+// and optionally a language code. Rows without a language fall back to
+// originalLanguage, so a Septuagint file needs the column to mark its Old
+// Testament words as Greek. Like the cross references, the file is only
+// loaded into an empty table, an empty path runs without it and a missing
+// file is an error.
+func loadInterlinear(path string) error {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM original_words`).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	if path == "" {
+		slog.Warn("No interlinear data configured; /api/original will return no results")
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w; run `go run scripts/fetch_data.go interlinear` to download it, or pass -interlinear= to run without original-language text", err)
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	stmt, err := tx.Prepare(`INSERT INTO original_words (bookId, chapter, verse, position, language, word, strongsNumber)
+	                         VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Split(scanner.Text(), "\t")
+		if line == 1 || len(fields) < 3 {
+			continue // Header row or blank line
+		}
+
+		ref, err := parseOSISRef(fields[0])
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		position, err := strconv.Atoi(fields[1])
+		if err != nil || position < 1 {
+			return fmt.Errorf("%s:%d: malformed word position %q", path, line, fields[1])
+		}
+		var strongsNumber string
+		if len(fields) > 3 {
+			var ok bool
+			if strongsNumber, ok = normalizeStrongsNumber(fields[3]); !ok {
+				return fmt.Errorf("%s:%d: malformed Strong's number %q", path, line, fields[3])
+			}
+		}
+		language := originalLanguage(ref.bookID)
+		if len(fields) > 4 && fields[4] != "" {
+			language = fields[4]
+		}
+
+		if _, err := stmt.Exec(ref.bookID, ref.chapter, ref.verse, position, language, fields[2], strongsNumber); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// originalHandler returns a verse in its original language with the Strong's
+// number of each word, from the interlinear data loaded at startup.
+func originalHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var ref [3]int
+	for i, name := range []string{"bookId", "chapter", "verse"} {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			writeJSONError(w, http.StatusBadRequest, "Missing required query parameters: bookId, chapter, verse")
+			return
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			writeJSONError(w, http.StatusBadRequest, name+" must be a positive integer")
+			return
+		}
+		ref[i] = n
+	}
+
+	query := `SELECT position, language, word, strongsNumber FROM original_words
+	          WHERE bookId = ? AND chapter = ? AND verse = ?
+	          ORDER BY position`
+	rows, err := db.QueryContext(r.Context(), query, ref[0], ref[1], ref[2])
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	v := OriginalVerse{BookID: ref[0], Chapter: ref[1], Verse: ref[2], Words: []OriginalWord{}}
+	var text []string
+	for rows.Next() {
+		var word OriginalWord
+		if err := rows.Scan(&word.Position, &v.Language, &word.Word, &word.StrongsNumber); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		v.Words = append(v.Words, word)
+		text = append(text, word.Word)
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if len(v.Words) == 0 {
+		writeJSONError(w, http.StatusNotFound, "No original-language text for this verse")
+		return
+	}
+	v.Text = strings.Join(text, " ")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadInterlinear(t *testing.T) {
+	useTestDB(t)
+	path := filepath.Join(t.TempDir(), "interlinear.tsv")
+	data := "Reference\tPosition\tWord\tStrongs\n" +
+		"Gen.1.1\t1\tבְּרֵאשִׁ֖ית\tH7225\n" +
+		"Gen.1.1\t2\tבָּרָ֣א\th1254\n" +
+		"John.1.1\t2\tἀρχῇ\tG746\n" +
+		"John.1.1\t1\tἘν\tG1722\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadInterlinear(path); err != nil {
+		t.Fatalf("loadInterlinear: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+		body  string
+	}{
+		{"Hebrew", "bookId=1&chapter=1&verse=1", http.StatusOK,
+			`{"bookId":1,"chapter":1,"verse":1,"language":"hbo","text":"בְּרֵאשִׁ֖ית בָּרָ֣א","words":[{"position":1,"word":"בְּרֵאשִׁ֖ית","strongsNumber":"H7225"},{"position":2,"word":"בָּרָ֣א","strongsNumber":"H1254"}]}` + "\n"},
+		{"Greek, in word order", "bookId=43&chapter=1&verse=1", http.StatusOK,
+			`{"bookId":43,"chapter":1,"verse":1,"language":"grc","text":"Ἐν ἀρχῇ","words":[{"position":1,"word":"Ἐν","strongsNumber":"G1722"},{"position":2,"word":"ἀρχῇ","strongsNumber":"G746"}]}` + "\n"},
+		{"verse without data", "bookId=1&chapter=1&verse=2", http.StatusNotFound, ""},
+		{"non-numeric verse", "bookId=1&chapter=1&verse=one", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(http.HandlerFunc(originalHandler), http.MethodGet, "/api/original?"+tt.query, "", nil)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Errorf("body = %s, want %s", rec.Body, tt.body)
+			}
+		})
+	}
+
+	// A seeded table is kept, so the file is no longer needed.
+	if err := loadInterlinear(filepath.Join(t.TempDir(), "gone.tsv")); err != nil {
+		t.Errorf("reloading into a seeded table: %v", err)
+	}
+}
+
+func TestLoadInterlinearMissingFile(t *testing.T) {
+	useTestDB(t)
+	if err := loadInterlinear(filepath.Join(t.TempDir(), "gone.tsv")); err == nil {
+		t.Error("missing file was not an error")
+	}
+	if err := loadInterlinear(""); err != nil {
+		t.Errorf("empty path: %v", err)
+	}
+}
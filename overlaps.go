@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// OverlapGroup is a set of highlights on one verse whose ranges overlap,
+// directly or through each other. Start and End are the union of the ranges,
+// which is what merging the group would cover, and OverlapStart and
+// OverlapEnd bound the text covered by more than one of them.
+type OverlapGroup struct {
+	VerseID      string      `json:"verseId"`
+	Start        int         `json:"start"`
+	End          int         `json:"end"`
+	OverlapStart int         `json:"overlapStart"`
+	OverlapEnd   int         `json:"overlapEnd"`
+	Highlights   []Highlight `json:"highlights"`
+}
+
+// overlapsHandler lists the overlapping highlights in a chapter, for cleaning
+// up duplicates. Like mergeOverlapping it only considers highlights on a
+// single verse, but it groups them regardless of type so the client can
+// decide which to merge and which to delete.
+func overlapsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	translation, bookID, chapter, err := parseChapterQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := `SELECT ` + highlightColumns + ` FROM highlights
+	          WHERE userId = ? AND translation = ? AND bookId = ? AND chapter = ?
+	              AND (endVerseId = '' OR endVerseId = verseId) AND deletedAt IS NULL
+	          ORDER BY ` + verseNumberSQL + `, start, rowid`
+	rows, err := db.QueryContext(r.Context(), query, userIDFromContext(r.Context()), translation, bookID, chapter)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	var highlights []Highlight
+	for rows.Next() {
+		h, err := scanHighlight(rows)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		highlights = append(highlights, h)
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(overlapGroups(highlights))
+}
+
+// overlapGroups sweeps highlights, sorted by verse and then start offset,
+// collecting each run that overlaps. Highlights overlapping nothing are left
+// out.
+func overlapGroups(highlights []Highlight) []OverlapGroup {
+	groups := []OverlapGroup{}
+	var g OverlapGroup
+	flush := func() {
+		if len(g.Highlights) > 1 {
+			groups = append(groups, g)
+		}
+	}
+	for _, h := range highlights {
+		// An empty range covers no text, so it overlaps nothing even when it
+		// sits inside another highlight.
+		if h.End <= h.Start {
+			continue
+		}
+		// As in mergeOverlapping, ranges that merely touch do not overlap.
+		if len(g.Highlights) > 0 && h.VerseID == g.VerseID && h.Start < g.End {
+			if len(g.Highlights) == 1 {
+				g.OverlapStart = h.Start
+			}
+			g.OverlapEnd = max(g.OverlapEnd, min(h.End, g.End))
+			g.End = max(g.End, h.End)
+			g.Highlights = append(g.Highlights, h)
+			continue
+		}
+		flush()
+		g = OverlapGroup{VerseID: h.VerseID, Start: h.Start, End: h.End, Highlights: []Highlight{h}}
+	}
+	flush()
+	return groups
+}
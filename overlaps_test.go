@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOverlapGroups(t *testing.T) {
+	h := func(id, verse string, start, end int) Highlight {
+		return Highlight{ID: id, VerseID: verse, Start: start, End: end}
+	}
+	type group struct {
+		start, end, overlapStart, overlapEnd int
+		ids                                  []string
+	}
+	tests := []struct {
+		name       string
+		highlights []Highlight
+		want       []group
+	}{
+		{"none", nil, nil},
+		{"touching ranges", []Highlight{h("a", "v1", 0, 5), h("b", "v1", 5, 10)}, nil},
+		{"simple overlap", []Highlight{h("a", "v1", 0, 6), h("b", "v1", 4, 10)},
+			[]group{{0, 10, 4, 6, []string{"a", "b"}}}},
+		{"chained overlaps", []Highlight{h("a", "v1", 0, 4), h("b", "v1", 3, 8), h("c", "v1", 7, 12), h("d", "v1", 12, 15)},
+			[]group{{0, 12, 3, 8, []string{"a", "b", "c"}}}},
+		{"contained range", []Highlight{h("a", "v1", 0, 20), h("b", "v1", 5, 10)},
+			[]group{{0, 20, 5, 10, []string{"a", "b"}}}},
+		{"different verses", []Highlight{h("a", "v1", 0, 10), h("b", "v2", 2, 8)}, nil},
+		{"separate groups", []Highlight{h("a", "v1", 0, 5), h("b", "v1", 2, 6), h("c", "v2", 0, 5), h("d", "v2", 1, 3)},
+			[]group{{0, 6, 2, 5, []string{"a", "b"}}, {0, 5, 1, 3, []string{"c", "d"}}}},
+		{"zero-length inside another", []Highlight{h("a", "v1", 0, 10), h("b", "v1", 4, 4)}, nil},
+		{"zero-length between overlaps", []Highlight{h("a", "v1", 0, 6), h("b", "v1", 3, 3), h("c", "v1", 4, 8)},
+			[]group{{0, 8, 4, 6, []string{"a", "c"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []group
+			for _, g := range overlapGroups(tt.highlights) {
+				var ids []string
+				for _, h := range g.Highlights {
+					ids = append(ids, h.ID)
+				}
+				got = append(got, group{g.Start, g.End, g.OverlapStart, g.OverlapEnd, ids})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("overlapGroups = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// pageCache keeps the raw HTML of fetched pages on disk, gzipped and named by
+// the SHA-256 of their URL. Keeping the pages lets the scraping be improved
+// and re-run without fetching them again, and lets lookups keep working when
+// the site is unreachable.
+type pageCache struct {
+	dir    string
+	maxAge time.Duration
+}
+
+// lexiconCache caches Blue Letter Bible pages when -lexicon-cache-dir is set;
+// nil disables it.
+var lexiconCache *pageCache
+
+func (c *pageCache) path(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".html.gz")
+}
+
+// get returns the cached page for rawURL, and whether it is younger than
+// maxAge. ok is false when there is no readable copy.
+func (c *pageCache) get(rawURL string) (page []byte, fresh, ok bool) {
+	f, err := os.Open(c.path(rawURL))
+	if err != nil {
+		return nil, false, false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, false, false
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, false, false
+	}
+	page, err = io.ReadAll(zr)
+	if err != nil {
+		return nil, false, false
+	}
+	return page, time.Since(info.ModTime()) < c.maxAge, true
+}
+
+// put stores page for rawURL. It writes to a temporary file first so that a
+// concurrent get never sees a partly written page.
+func (c *pageCache) put(rawURL string, page []byte) error {
+	tmp, err := os.CreateTemp(c.dir, "page-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op once the file has been renamed
+
+	zw := gzip.NewWriter(tmp)
+	if _, err := zw.Write(page); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(rawURL))
+}
+
+// cachedResponse wraps a cached page as a successful response.
+func cachedResponse(page []byte) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/html"}},
+		Body:       io.NopCloser(bytes.NewReader(page)),
+	}
+}
+
+// fetchPage is fetch for Blue Letter Bible pages, going through lexiconCache
+// when it is enabled. A fresh cached page is used without a request; a stale
+// one is refreshed, but still used if the site cannot be reached or answers
+// with a server error.
+func fetchPage(ctx context.Context, rawURL string) (*http.Response, error) {
+	if lexiconCache == nil {
+		return fetch(ctx, rawURL)
+	}
+
+	cached, fresh, ok := lexiconCache.get(rawURL)
+	if ok && fresh {
+		return cachedResponse(cached), nil
+	}
+
+	res, err := fetch(ctx, rawURL)
+	if ok && (err != nil || res.StatusCode >= 500) {
+		if err == nil {
+			res.Body.Close()
+		}
+		slog.Warn("Using stale cached page", "url", rawURL)
+		return cachedResponse(cached), nil
+	}
+	if err != nil || res.StatusCode != http.StatusOK {
+		return res, err
+	}
+
+	page, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if err := lexiconCache.put(rawURL, page); err != nil {
+		slog.Error("Page cache write failed", "err", err, "url", rawURL)
+	}
+	res.Body = io.NopCloser(bytes.NewReader(page))
+	return res, nil
+}
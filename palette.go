@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// maxPaletteBodyBytes bounds the body of a palette update, which holds one
+// short color per highlight type.
+const maxPaletteBodyBytes = 16 << 10
+
+// Palette maps highlight types to the hex color a user shows them in.
+type Palette map[string]string
+
+// check normalizes the colors of p in place with normalizeColor and returns a
+// message describing the first entry with an unknown type or invalid color.
+func (p Palette) check() string {
+	for t, color := range p {
+		if !isValidHighlightType(t) {
+			return invalidTypeMessage(t)
+		}
+		if strings.TrimSpace(color) == "" {
+			return fmt.Sprintf("Color for %s must not be empty", t)
+		}
+		normalized, err := normalizeColor(color)
+		if err != nil {
+			return err.Error()
+		}
+		p[t] = normalized
+	}
+	return ""
+}
+
+func paletteHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		getPaletteHandler(w, r)
+	case http.MethodPut:
+		savePaletteHandler(w, r)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// queryRower is a *sql.DB or *sql.Tx, so that palettes can be read inside
+// the transaction of an import or copy as well as outside one.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// loadPalette returns the user's palette for translation, or nil if they have
+// not saved one.
+func loadPalette(ctx context.Context, q queryRower, userID int64, translation string) (Palette, error) {
+	var colors string
+	err := q.QueryRowContext(ctx, `SELECT colors FROM palettes WHERE userId = ? AND translation = ?`, userID, translation).Scan(&colors)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p Palette
+	if err := json.Unmarshal([]byte(colors), &p); err != nil {
+		return nil, fmt.Errorf("decoding palette: %w", err)
+	}
+	return p, nil
+}
+
+// getPaletteHandler returns the user's palette for a translation, or an
+// empty one if they have not saved one.
+func getPaletteHandler(w http.ResponseWriter, r *http.Request) {
+	translation := r.URL.Query().Get("translation")
+	if translation == "" {
+		writeJSONError(w, http.StatusBadRequest, "Missing required query parameter: translation")
+		return
+	}
+
+	p, err := loadPalette(r.Context(), db, userIDFromContext(r.Context()), translation)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if p == nil {
+		p = Palette{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// savePaletteHandler replaces the user's palette for a translation.
+func savePaletteHandler(w http.ResponseWriter, r *http.Request) {
+	translation := r.URL.Query().Get("translation")
+	if translation == "" {
+		writeJSONError(w, http.StatusBadRequest, "Missing required query parameter: translation")
+		return
+	}
+
+	var p Palette
+	r.Body = http.MaxBytesReader(w, r.Body, maxPaletteBodyBytes)
+	err := json.NewDecoder(r.Body).Decode(&p)
+	if isBodyTooLarge(err) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, bodyTooLargeMessage(maxPaletteBodyBytes))
+		return
+	}
+	if err != nil || p == nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body: expected an object of type to color")
+		return
+	}
+	if msg := p.check(); msg != "" {
+		writeJSONError(w, http.StatusUnprocessableEntity, msg)
+		return
+	}
+	colors, err := json.Marshal(p)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to encode palette")
+		slog.Error("Palette encoding failed", "err", err)
+		return
+	}
+
+	query := `INSERT INTO palettes (userId, translation, colors, updatedAt) VALUES (?, ?, ?, ?)
+	          ON CONFLICT(userId, translation) DO UPDATE SET colors = excluded.colors, updatedAt = excluded.updatedAt`
+	if _, err := db.ExecContext(r.Context(), query, userIDFromContext(r.Context()), translation, string(colors), timestamp()); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// applyPalette checks h's color against the user's saved palette for its
+// translation, read through q. A highlight without a color takes the
+// palette's color for its type; one with a different color is rejected with
+// a message for the client. Without a saved palette, or a palette entry for
+// the type, any color is allowed.
+func applyPalette(ctx context.Context, q queryRower, h *Highlight) (string, error) {
+	p, err := loadPalette(ctx, q, h.UserID, h.Translation)
+	if err != nil {
+		return "", err
+	}
+	color, ok := p[h.Type]
+	switch {
+	case !ok:
+	case h.Color == "":
+		h.Color = color
+	case !strings.EqualFold(h.Color, color):
+		return fmt.Sprintf("Color %q does not match the palette color %s for %s highlights", h.Color, color, h.Type), nil
+	}
+	return "", nil
+}
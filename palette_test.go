@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPaletteAppliesToEveryWayOfCreating(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+	if rec := serve(mux, http.MethodPut, "/api/palette?translation=KJV", `{"highlight-only":"#00ff00"}`, ann); rec.Code != http.StatusOK {
+		t.Fatalf("saving palette: status %d: %s", rec.Code, rec.Body)
+	}
+	uncolored := func(id string) string { return strings.Replace(testHighlight(id), `"#ffff00"`, `""`, 1) }
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		want   int
+		reply  string // substring of the response
+	}{
+		{"bulk with a clashing color", "/api/highlights/bulk", `[` + uncolored("b-1") + `,` + testHighlight("b-2") + `]`, http.StatusUnprocessableEntity, "index 1"},
+		{"bulk without colors", "/api/highlights/bulk", `[` + uncolored("b-1") + `]`, http.StatusCreated, `"inserted":1`},
+		{"import with a clashing color", "/api/highlights/import", `[` + uncolored("i-1") + `,` + testHighlight("i-2") + `]`, http.StatusOK, `"skipped":[{"index":1,"reason":"Color \"#ffff00\" does not match`},
+		{"highlight in another translation", "/api/highlights", strings.Replace(testHighlight("web-1"), `"KJV"`, `"WEB"`, 1), http.StatusCreated, `"color":"#ffff00"`},
+		{"copy into the palette's translation", "/api/highlights/copy", `{"fromTranslation":"WEB","toTranslation":"KJV","bookId":1,"chapter":1}`, http.StatusCreated, `"copied":0,"skipped":[{"index":0,"reason":"web-1: Color`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(mux, http.MethodPost, tt.target, tt.body, ann)
+			if rec.Code != tt.want || !strings.Contains(rec.Body.String(), tt.reply) {
+				t.Errorf("got %d %s, want %d containing %s", rec.Code, rec.Body, tt.want, tt.reply)
+			}
+		})
+	}
+
+	for _, id := range []string{"b-1", "i-1"} {
+		rec := serve(mux, http.MethodGet, "/api/highlights/"+id, "", ann)
+		if !strings.Contains(rec.Body.String(), `"color":"#00ff00"`) {
+			t.Errorf("%s did not take the palette color: %s", id, rec.Body)
+		}
+	}
+	if rec := serve(mux, http.MethodGet, "/api/highlights/i-2", "", ann); rec.Code != http.StatusNotFound {
+		t.Errorf("skipped import row was stored: status %d", rec.Code)
+	}
+}
+
+func TestPaletteAppliesToUpdates(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+	if rec := serve(mux, http.MethodPost, "/api/highlights", testHighlight("h-1"), ann); rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(mux, http.MethodPut, "/api/palette?translation=KJV", `{"highlight-only":"#ffff00","note":"#00ff00"}`, ann); rec.Code != http.StatusOK {
+		t.Fatalf("saving palette: status %d: %s", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		name  string
+		body  string
+		want  int
+		reply string // substring of the response
+	}{
+		{"clashing color", `{"color":"#ff0000"}`, http.StatusUnprocessableEntity, "does not match the palette color #ffff00"},
+		{"type whose palette color clashes", `{"type":"note"}`, http.StatusUnprocessableEntity, "does not match the palette color #00ff00"},
+		{"palette color", `{"type":"note","color":"#00FF00"}`, http.StatusOK, `"color":"#00ff00"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(mux, http.MethodPut, "/api/highlights/h-1", tt.body, ann)
+			if rec.Code != tt.want || !strings.Contains(rec.Body.String(), tt.reply) {
+				t.Errorf("got %d %s, want %d containing %s", rec.Code, rec.Body, tt.want, tt.reply)
+			}
+		})
+	}
+}
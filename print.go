@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// printSegment is a run of verse text covered by the same highlights.
+type printSegment struct {
+	Text      string
+	Highlight bool
+	Underline bool
+	Noted     bool
+	Color     string
+	NoteRefs  []int // Footnote numbers of notes ending with this segment
+}
+
+// Class returns the CSS classes for the segment's span.
+func (s printSegment) Class() string {
+	var classes []string
+	if s.Highlight {
+		classes = append(classes, "highlight")
+	}
+	if s.Underline {
+		classes = append(classes, "underline")
+	}
+	if s.Noted {
+		classes = append(classes, "noted")
+	}
+	return strings.Join(classes, " ")
+}
+
+type printVerse struct {
+	Number   int
+	Segments []printSegment
+}
+
+type printNote struct {
+	Number int
+	Verse  int
+	Text   string
+}
+
+type printPage struct {
+	Title       string
+	Translation string
+	Verses      []printVerse
+	Notes       []printNote
+}
+
+// printHandler renders a chapter with the user's highlights applied inline
+// and notes collected as footnotes, for printing or saving as PDF.
+func printHandler(w http.ResponseWriter, r *http.Request) {
+	translation, bookID, chapter, err := parseChapterQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := db.QueryContext(r.Context(), `SELECT `+highlightColumns+` FROM highlights
+	                       WHERE userId = ? AND translation = ? AND bookId = ? AND chapter <= ? AND endChapter >= ? AND deletedAt IS NULL ORDER BY rowid`,
+		userIDFromContext(r.Context()), translation, bookID, chapter, chapter)
+	if err != nil {
+		http.Error(w, "Database query failed", http.StatusInternalServerError)
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	var highlights []Highlight
+	for rows.Next() {
+		h, err := scanHighlight(rows)
+		if err != nil {
+			http.Error(w, "Failed to scan row", http.StatusInternalServerError)
+			slog.Error("Database error", "err", err)
+			return
+		}
+		highlights = append(highlights, h)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Database query failed", http.StatusInternalServerError)
+		slog.Error("Database error", "err", err)
+		return
+	}
+	renderPrintPage(w, r, translation, bookID, chapter, highlights)
+}
+
+// renderPrintPage writes the print page for a chapter with highlights
+// applied, or an error page if the chapter's text cannot be loaded.
+func renderPrintPage(w http.ResponseWriter, r *http.Request, translation string, bookID, chapter int, highlights []Highlight) {
+	verses, err := loadChapterVerses(r.Context(), translation, bookID, chapter)
+	if err != nil {
+		http.Error(w, "Failed to load verses", http.StatusInternalServerError)
+		slog.Error("Verse load failed", "err", err)
+		return
+	}
+	if len(verses) == 0 {
+		http.Error(w, "Chapter not found", http.StatusNotFound)
+		return
+	}
+
+	byVerse := highlightsByVerse(highlights, verses)
+	page := printPage{Title: fmt.Sprintf("%s %d", bookName(bookID), chapter), Translation: translation}
+	for _, v := range verses {
+		pv := printVerse{Number: v.VerseNumber}
+		pv.Segments = printSegments(v, byVerse[v.VerseID], &page.Notes)
+		page.Verses = append(page.Verses, pv)
+	}
+
+	t, err := templates()
+	if err != nil {
+		http.Error(w, "Failed to parse templates", http.StatusInternalServerError)
+		slog.Error("Template error", "err", err)
+		return
+	}
+	if err := t.ExecuteTemplate(w, "print.html", page); err != nil {
+		slog.Error("Template error", "err", err)
+	}
+}
+
+// highlightsByVerse groups highlights by the verse of the chapter they are
+// drawn on. A span becomes one piece per verse it covers here: from its start
+// offset in the first, the whole of any in between and up to its end offset
+// in the last. Only the final piece keeps the note, so its footnote marker
+// comes where the span ends or leaves the chapter.
+//
+// As in the frontend, an empty range marks a verse-level highlight, such as
+// a copy from another translation or a YouVersion import, and covers the
+// whole verse. A span's offsets are in different verses, so there only an
+// end of 0 means the whole of the last verse.
+func highlightsByVerse(highlights []Highlight, verses []Verse) map[string][]Highlight {
+	before := func(c1, v1, c2, v2 int) bool { return c1 < c2 || (c1 == c2 && v1 < v2) }
+
+	byVerse := map[string][]Highlight{}
+	for _, h := range highlights {
+		_, startChapter, startVerse, startOK := parseVerseID(h.VerseID)
+		_, endChapter, endVerse, endOK := parseVerseID(h.EndVerseID)
+		if h.EndVerseID == "" || h.EndVerseID == h.VerseID || !startOK || !endOK {
+			if h.End <= h.Start {
+				h.End = math.MaxInt
+			}
+			byVerse[h.VerseID] = append(byVerse[h.VerseID], h)
+			continue
+		}
+		if h.End == 0 {
+			h.End = math.MaxInt
+		}
+
+		var last string
+		for _, v := range verses {
+			_, c, n, ok := parseVerseID(v.VerseID)
+			if !ok || before(c, n, startChapter, startVerse) || before(endChapter, endVerse, c, n) {
+				continue
+			}
+			piece := h
+			piece.Note = ""
+			if c != startChapter || n != startVerse {
+				piece.Start = 0
+			}
+			if c != endChapter || n != endVerse {
+				piece.End = math.MaxInt
+			}
+			byVerse[v.VerseID] = append(byVerse[v.VerseID], piece)
+			last = v.VerseID
+		}
+		if last != "" {
+			pieces := byVerse[last]
+			pieces[len(pieces)-1].Note = h.Note
+		}
+	}
+	return byVerse
+}
+
+// bookName returns the English name of bookID, or a placeholder for an ID
+// outside the canon.
+func bookName(bookID int) string {
+	if bookID >= 1 && bookID <= len(bookNames) {
+		return bookNames[bookID-1]
+	}
+	return "Book " + strconv.Itoa(bookID)
+}
+
+// printSegments splits a verse's plain text at every highlight boundary.
+//
+// Highlight offsets are recorded by the frontend as UTF-16 positions in the
+// verse paragraph's text, which begins with the verse number, so the same
+// prefix is counted here before the verse text. Notes are appended to notes
+// and referenced from the segment where they end.
+func printSegments(v Verse, highlights []Highlight, notes *[]printNote) []printSegment {
+	text := verseText(v.Text)
+	prefix := len(utf16.Encode([]rune(strconv.Itoa(v.VerseNumber))))
+	units := utf16.Encode([]rune(text))
+	clamp := func(offset int) int {
+		return min(max(offset-prefix, 0), len(units))
+	}
+
+	bounds := []int{0, len(units)}
+	for _, h := range highlights {
+		bounds = append(bounds, clamp(h.Start), clamp(h.End))
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+
+	noteRefs := map[int][]int{} // segment end offset -> footnote numbers
+	for _, h := range highlights {
+		if h.Type == "note" && h.Note != "" {
+			n := len(*notes) + 1
+			*notes = append(*notes, printNote{Number: n, Verse: v.VerseNumber, Text: h.Note})
+			noteRefs[clamp(h.End)] = append(noteRefs[clamp(h.End)], n)
+		}
+	}
+
+	var segments []printSegment
+	for i := 0; i+1 < len(bounds); i++ {
+		start, end := bounds[i], bounds[i+1]
+		seg := printSegment{Text: string(utf16.Decode(units[start:end])), NoteRefs: noteRefs[end]}
+		for _, h := range highlights {
+			if clamp(h.Start) > start || clamp(h.End) < end {
+				continue
+			}
+			switch h.Type {
+			case "note":
+				seg.Noted = true
+			case "underline":
+				seg.Underline = true
+			case "highlight-only":
+				seg.Highlight = true
+				if h.Color != "" {
+					seg.Color = h.Color
+				}
+			}
+		}
+		segments = append(segments, seg)
+	}
+	return segments
+}
+
+// verseText returns the text content of a verse as the browser would see it,
+// with the markup bolls.life includes removed and entities decoded.
+func verseText(html string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return html
+	}
+	return doc.Text()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHighlightsByVerseSplitsSpans(t *testing.T) {
+	verses := []Verse{{VerseID: "verse-1-2-1"}, {VerseID: "verse-1-2-2"}, {VerseID: "verse-1-2-3"}, {VerseID: "verse-1-2-4"}}
+	highlights := []Highlight{
+		{ID: "single", VerseID: "verse-1-2-4", Start: 2, End: 6},
+		{ID: "into", VerseID: "verse-1-1-31", EndVerseID: "verse-1-2-2", Start: 3, End: 9, Type: "note", Note: "rest"},
+		{ID: "within", VerseID: "verse-1-2-3", EndVerseID: "verse-1-2-4", Start: 4, End: 7},
+		{ID: "copied", VerseID: "verse-1-2-1"},
+	}
+
+	got := highlightsByVerse(highlights, verses)
+
+	type piece struct {
+		id         string
+		start, end int
+		note       string
+	}
+	want := map[string][]piece{
+		"verse-1-2-1": {{"into", 0, math.MaxInt, ""}, {"copied", 0, math.MaxInt, ""}},
+		"verse-1-2-2": {{"into", 0, 9, "rest"}},
+		"verse-1-2-3": {{"within", 4, math.MaxInt, ""}},
+		"verse-1-2-4": {{"single", 2, 6, ""}, {"within", 0, 7, ""}},
+	}
+	for verse, pieces := range want {
+		if len(got[verse]) != len(pieces) {
+			t.Errorf("%s has %d pieces, want %d: %+v", verse, len(got[verse]), len(pieces), got[verse])
+			continue
+		}
+		for i, p := range pieces {
+			h := got[verse][i]
+			if h.ID != p.id || h.Start != p.start || h.End != p.end || h.Note != p.note {
+				t.Errorf("%s piece %d = %s %d-%d %q, want %s %d-%d %q", verse, i, h.ID, h.Start, h.End, h.Note, p.id, p.start, p.end, p.note)
+			}
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("pieces on %d verses, want %d", len(got), len(want))
+	}
+}
+
+func TestPrintSegmentsDrawsVerseLevelHighlights(t *testing.T) {
+	verses := []Verse{{VerseID: "verse-43-3-16", VerseNumber: 16, Text: "For God so loved the world"}}
+	highlights := []Highlight{{ID: "copied", Type: "highlight-only", VerseID: "verse-43-3-16", Color: "#ffff00"}}
+
+	var notes []printNote
+	segments := printSegments(verses[0], highlightsByVerse(highlights, verses)["verse-43-3-16"], &notes)
+	if len(segments) != 1 || segments[0].Text != "For God so loved the world" || !segments[0].Highlight {
+		t.Errorf("segments = %+v, want the whole verse highlighted", segments)
+	}
+}
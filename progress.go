@@ -0,0 +1,81 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// ReadingProgress is the last position a user read to.
+type ReadingProgress struct {
+	Translation string `json:"translation"`
+	BookID      int    `json:"bookId"`
+	Chapter     int    `json:"chapter"`
+	Verse       int    `json:"verse"`
+	UpdatedAt   string `json:"updatedAt"`
+}
+
+func progressHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		getProgressHandler(w, r)
+	case http.MethodPost:
+		saveProgressHandler(w, r)
+	default:
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// getProgressHandler returns the user's saved position, or 404 if they have
+// not saved one yet.
+func getProgressHandler(w http.ResponseWriter, r *http.Request) {
+	var p ReadingProgress
+	err := db.QueryRowContext(r.Context(), `SELECT translation, bookId, chapter, verse, updatedAt FROM reading_progress WHERE userId = ?`, userIDFromContext(r.Context())).
+		Scan(&p.Translation, &p.BookID, &p.Chapter, &p.Verse, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		writeJSONError(w, http.StatusNotFound, "No reading progress saved")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// saveProgressHandler records the user's current position, replacing any
+// earlier one. updatedAt is set by the server.
+func saveProgressHandler(w http.ResponseWriter, r *http.Request) {
+	var p ReadingProgress
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if p.Translation == "" || p.BookID <= 0 || p.Chapter <= 0 {
+		writeJSONError(w, http.StatusBadRequest, "translation, bookId and chapter are required")
+		return
+	}
+	if p.Verse <= 0 {
+		p.Verse = 1
+	}
+	p.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	query := `INSERT INTO reading_progress (userId, translation, bookId, chapter, verse, updatedAt)
+	          VALUES (?, ?, ?, ?, ?, ?)
+	          ON CONFLICT(userId) DO UPDATE SET
+	              translation = excluded.translation, bookId = excluded.bookId, chapter = excluded.chapter,
+	              verse = excluded.verse, updatedAt = excluded.updatedAt`
+	if _, err := db.ExecContext(r.Context(), query, userIDFromContext(r.Context()), p.Translation, p.BookID, p.Chapter, p.Verse, p.UpdatedAt); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Strong's lookups scrape Blue Letter Bible, so each client IP is limited to
+// a short burst and then a steady trickle, keeping the server's own IP from
+// being throttled upstream.
+const (
+	strongsRatePerSecond = 0.5
+	strongsRateBurst     = 10
+)
+
+// A batch lookup preloads a whole chapter, so /api/strongs/batch has its own
+// limit counted in batches rather than words: a few chapters in a row, then
+// one every ten seconds.
+const (
+	strongsBatchRatePerSecond = 0.1
+	strongsBatchRateBurst     = 5
+)
+
+// Verse text is fetched from bolls.life the first time each chapter is read,
+// so /api/verses is limited too, though generously enough for paging through
+// chapters.
+const (
+	versesRatePerSecond = 2
+	versesRateBurst     = 30
+)
+
+// limiterIdleTimeout is how long an IP can go without requests before its
+// bucket is dropped. A bucket idle this long has refilled anyway.
+const limiterIdleTimeout = 10 * time.Minute
+
+// tokenBucket holds up to burst tokens and refills at rate tokens per second.
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// ipRateLimiter keeps a token bucket per client IP.
+type ipRateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// newIPRateLimiter creates a limiter and starts a goroutine that evicts idle
+// buckets for the life of the process.
+func newIPRateLimiter(rate float64, burst int) *ipRateLimiter {
+	l := &ipRateLimiter{rate: rate, burst: float64(burst), buckets: map[string]*tokenBucket{}}
+	go func() {
+		for range time.Tick(limiterIdleTimeout) {
+			l.evictIdle(time.Now())
+		}
+	}()
+	return l
+}
+
+// allow takes a token for ip. When none is available it returns false and how
+// long until one will be.
+func (l *ipRateLimiter) allow(ip string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[ip]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst}
+		l.buckets[ip] = b
+	} else {
+		b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.lastSeen).Seconds()*l.rate)
+	}
+	b.lastSeen = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+func (l *ipRateLimiter) evictIdle(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for ip, b := range l.buckets {
+		if now.Sub(b.lastSeen) > limiterIdleTimeout {
+			delete(l.buckets, ip)
+		}
+	}
+}
+
+// rateLimit rejects requests from clients that have used up their tokens
+// with 429 and a Retry-After header in whole seconds.
+func rateLimit(l *ipRateLimiter, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ok, wait := l.allow(clientIP(r), time.Now())
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeJSONError(w, http.StatusTooManyRequests, "Too many requests; please slow down")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// clientIP returns the IP address of the peer that sent r.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"html"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// definitionPolicy keeps paragraphs, line breaks and inline emphasis from a
+// scraped definition, without any attributes, so nothing in the output can
+// run script, load a resource or link anywhere. Other elements are removed
+// and their text kept, except for those like script and style whose content
+// is dropped with them.
+var definitionPolicy = bluemonday.NewPolicy().AllowElements("p", "br", "em", "i", "strong", "b", "sup", "sub")
+
+// sanitizeDefinitionHTML reduces an HTML fragment from a lexicon page to the
+// markup definitionPolicy allows. Text is re-escaped, so the result is safe
+// to insert into a page as HTML.
+func sanitizeDefinitionHTML(fragment string) string {
+	return strings.TrimSpace(definitionPolicy.Sanitize(fragment))
+}
+
+// textDefinitionHTML renders a plain-text definition, with paragraphs
+// separated by blank lines, as HTML paragraphs.
+func textDefinitionHTML(text string) string {
+	var b strings.Builder
+	for _, para := range strings.Split(text, "\n\n") {
+		if para = strings.TrimSpace(para); para != "" {
+			b.WriteString("<p>" + strings.ReplaceAll(html.EscapeString(para), "\n", "<br>") + "</p>")
+		}
+	}
+	return b.String()
+}
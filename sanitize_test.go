@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func TestSanitizeDefinitionHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		fragment string
+		want     string
+	}{
+		{"formatting is kept", `<p>To <em>love</em>, <b>cherish</b>.</p><p>H<sub>2</sub><br>x<sup>2</sup></p>`,
+			`<p>To <em>love</em>, <b>cherish</b>.</p><p>H<sub>2</sub><br>x<sup>2</sup></p>`},
+		{"script", `<p>love<script>alert(1)</script></p>`, `<p>love</p>`},
+		{"script split by a comment", `<scr<!-- -->ipt>alert(1)</script>`, `ipt&gt;alert(1)`},
+		{"javascript link", `<a href="javascript:alert(1)">love</a>`, `love`},
+		{"event handlers", `<p onclick="alert(1)"><em onmouseover="alert(1)">love</em></p><img src=x onerror="alert(1)">`,
+			`<p><em>love</em></p>`},
+		{"style attribute", `<b style="background:url(javascript:alert(1))">love</b>`, `<b>love</b>`},
+		{"style element", `<style>body{background:url(javascript:alert(1))}</style>love`, `love`},
+		{"svg", `<svg onload="alert(1)"><script>alert(1)</script><a xlink:href="javascript:alert(1)"><text>x</text></a></svg>love`, `xlove`},
+		{"math", `<math><mi xlink:href="javascript:alert(1)">x</mi></math>love`, `xlove`},
+		{"iframe", `<iframe src="javascript:alert(1)"></iframe><iframe srcdoc="<script>alert(1)</script>"></iframe>love`, `love`},
+		// The policy filters tags without balancing them; a browser inserting
+		// the result closes and nests them as it would in the source page.
+		{"unclosed tags", `<p><em>love<b>joy`, `<p><em>love<b>joy`},
+		{"misnested tags", `<em><b>love</em> joy</b>`, `<em><b>love</em> joy</b>`},
+		{"stray end tags", `</p></em>love</script>`, `</p></em>love`},
+		{"markup in raw text elements", `<textarea><img src=x onerror=alert(1)></textarea><title></title><script>x</script></title>`,
+			`&lt;img src=x onerror=alert(1)&gt;`},
+		{"entities stay escaped", `&lt;script&gt;alert(1)&lt;/script&gt; &amp; <!-- <script> -->`,
+			`&lt;script&gt;alert(1)&lt;/script&gt; &amp;`},
+		{"unterminated tag", `love<img src="x" onerror="alert(1)"`, `love`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sanitizeDefinitionHTML(tt.fragment)
+			if got != tt.want {
+				t.Errorf("sanitizeDefinitionHTML(%q) = %q, want %q", tt.fragment, got, tt.want)
+			}
+			assertOnlyAllowedMarkup(t, got)
+		})
+	}
+}
+
+// definitionAllowedTags are the elements definitionPolicy allows.
+var definitionAllowedTags = map[atom.Atom]bool{
+	atom.P: true, atom.Br: true,
+	atom.Em: true, atom.I: true, atom.Strong: true, atom.B: true,
+	atom.Sup: true, atom.Sub: true,
+}
+
+// assertOnlyAllowedMarkup fails t unless s parses back to nothing but text and
+// attribute-free elements from definitionAllowedTags.
+func assertOnlyAllowedMarkup(t *testing.T, s string) {
+	t.Helper()
+	parent := &xhtml.Node{Type: xhtml.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := xhtml.ParseFragment(strings.NewReader(s), parent)
+	if err != nil {
+		t.Fatalf("output does not parse: %v", err)
+	}
+	var check func(n *xhtml.Node)
+	check = func(n *xhtml.Node) {
+		switch n.Type {
+		case xhtml.TextNode:
+		case xhtml.ElementNode:
+			if !definitionAllowedTags[n.DataAtom] || n.Namespace != "" || len(n.Attr) > 0 {
+				t.Errorf("output %q contains <%s> with attributes %v", s, n.Data, n.Attr)
+			}
+		default:
+			t.Errorf("output %q contains a node of type %d", s, n.Type)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			check(c)
+		}
+	}
+	for _, n := range nodes {
+		check(n)
+	}
+}
+
+func TestTextDefinitionHTML(t *testing.T) {
+	got := textDefinitionHTML("to love\n<script>alert(1)</script>\n\n\n\n  to cherish  \n\n")
+	want := `<p>to love<br>&lt;script&gt;alert(1)&lt;/script&gt;</p><p>to cherish</p>`
+	if got != want {
+		t.Errorf("textDefinitionHTML = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,298 @@
+//go:build ignore
+
+// Command fetch_data downloads the open datasets that bible_app loads at
+// startup into data/, where the default flags look for them. Run it from the
+// repository root, naming the datasets to fetch or none for all of them:
+//
+//	go run scripts/fetch_data.go [crossrefs] [lexicon] [interlinear]
+//
+// See data/README.md for where each dataset comes from and its license.
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// datasets lists what can be fetched, in the order they are fetched when no
+// names are given.
+var datasets = []struct {
+	name  string
+	fetch func() error
+}{
+	{"crossrefs", fetchCrossReferences},
+	{"lexicon", fetchLexicon},
+	{"interlinear", fetchInterlinear},
+}
+
+var client = &http.Client{Timeout: 5 * time.Minute}
+
+func main() {
+	names := os.Args[1:]
+	if len(names) == 0 {
+		for _, d := range datasets {
+			names = append(names, d.name)
+		}
+	}
+
+	for _, name := range names {
+		found := false
+		for _, d := range datasets {
+			if d.name != name {
+				continue
+			}
+			found = true
+			fmt.Fprintf(os.Stderr, "Fetching %s...\n", name)
+			if err := d.fetch(); err != nil {
+				fmt.Fprintf(os.Stderr, "fetch_data: %s: %v\n", name, err)
+				os.Exit(1)
+			}
+		}
+		if !found {
+			fmt.Fprintf(os.Stderr, "fetch_data: unknown dataset %q\n", name)
+			os.Exit(2)
+		}
+	}
+}
+
+// download returns the body of rawURL.
+func download(rawURL string) ([]byte, error) {
+	res, err := client.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", rawURL, res.Status)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// writeData replaces data/name with data, so an interrupted fetch never
+// leaves a truncated file behind for the server to load.
+func writeData(name string, data []byte) error {
+	path := filepath.Join("data", name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s (%d bytes)\n", path, len(data))
+	return os.Rename(tmp, path)
+}
+
+// fetchCrossReferences downloads the OpenBible.info cross references, which
+// are published as a zip holding a single tab-separated file.
+func fetchCrossReferences() error {
+	archive, err := download("https://a.openbible.info/data/cross-references.zip")
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		if filepath.Base(f.Name) != "cross_references.txt" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			return err
+		}
+		return writeData("cross_references.txt", data)
+	}
+	return fmt.Errorf("cross_references.txt not found in the archive")
+}
+
+// strongsDictionaries are the Open Scriptures Strong's dictionaries, which are
+// JavaScript files each assigning one object keyed by Strong's number.
+var strongsDictionaries = []string{
+	"https://raw.githubusercontent.com/openscriptures/strongs/master/greek/strongs-greek-dictionary.js",
+	"https://raw.githubusercontent.com/openscriptures/strongs/master/hebrew/strongs-hebrew-dictionary.js",
+}
+
+// fetchLexicon combines the Greek and Hebrew dictionaries into the single
+// JSON object the server reads. The Hebrew entries call the transliteration
+// "xlit", so it is copied to "translit" to match the Greek.
+func fetchLexicon() error {
+	lexicon := map[string]map[string]any{}
+	for _, rawURL := range strongsDictionaries {
+		script, err := download(rawURL)
+		if err != nil {
+			return err
+		}
+		start, end := bytes.Index(script, []byte("= {")), bytes.LastIndexByte(script, '}')
+		if start < 0 || end < start {
+			return fmt.Errorf("%s: no dictionary object found", rawURL)
+		}
+		var entries map[string]map[string]any
+		if err := json.Unmarshal(script[start+2:end+1], &entries); err != nil {
+			return fmt.Errorf("%s: %w", rawURL, err)
+		}
+		for number, entry := range entries {
+			if _, ok := entry["translit"]; !ok {
+				entry["translit"] = entry["xlit"]
+			}
+			lexicon[number] = entry
+		}
+	}
+
+	data, err := json.Marshal(lexicon)
+	if err != nil {
+		return err
+	}
+	return writeData("strongs.json", data)
+}
+
+// osisBooks are the OSIS abbreviations of the 66 books, as in books.go. The
+// Open Scriptures Hebrew Bible names its files after the first 39.
+var osisBooks = [66]string{
+	"Gen", "Exod", "Lev", "Num", "Deut", "Josh", "Judg", "Ruth", "1Sam", "2Sam",
+	"1Kgs", "2Kgs", "1Chr", "2Chr", "Ezra", "Neh", "Esth", "Job", "Ps", "Prov",
+	"Eccl", "Song", "Isa", "Jer", "Lam", "Ezek", "Dan", "Hos", "Joel", "Amos",
+	"Obad", "Jonah", "Mic", "Nah", "Hab", "Zeph", "Hag", "Zech", "Mal",
+	"Matt", "Mark", "Luke", "John", "Acts", "Rom", "1Cor", "2Cor", "Gal", "Eph",
+	"Phil", "Col", "1Thess", "2Thess", "1Tim", "2Tim", "Titus", "Phlm", "Heb", "Jas",
+	"1Pet", "2Pet", "1John", "2John", "3John", "Jude", "Rev",
+}
+
+// ugntBooks are the USFM codes of the New Testament books, which name the
+// files of the unfoldingWord Greek New Testament along with their number.
+var ugntBooks = [27]string{
+	"MAT", "MRK", "LUK", "JHN", "ACT", "ROM", "1CO", "2CO", "GAL", "EPH",
+	"PHP", "COL", "1TH", "2TH", "1TI", "2TI", "TIT", "PHM", "HEB", "JAS",
+	"1PE", "2PE", "1JN", "2JN", "3JN", "JUD", "REV",
+}
+
+// fetchInterlinear builds the interlinear file from the Open Scriptures
+// Hebrew Bible (the Westminster Leningrad Codex) for the Old Testament and
+// the unfoldingWord Greek New Testament, both of which tag every word with
+// its Strong's number. The server works out the language from the book.
+func fetchInterlinear() error {
+	var b bytes.Buffer
+	b.WriteString("Reference\tPosition\tWord\tStrongs\n")
+	for _, book := range osisBooks[:39] {
+		data, err := download("https://raw.githubusercontent.com/openscriptures/morphhb/master/wlc/" + book + ".xml")
+		if err != nil {
+			return err
+		}
+		if err := writeHebrewWords(&b, data); err != nil {
+			return fmt.Errorf("%s: %w", book, err)
+		}
+	}
+	for i, code := range ugntBooks {
+		name := fmt.Sprintf("%d-%s.usfm", 41+i, code)
+		data, err := download("https://git.door43.org/unfoldingWord/el-x-koine_ugnt/raw/branch/master/" + name)
+		if err != nil {
+			return err
+		}
+		if err := writeGreekWords(&b, osisBooks[39+i], data); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return writeData("interlinear.tsv", b.Bytes())
+}
+
+// writeHebrewWords appends a row for each <w> element of an OSIS book from
+// the Open Scriptures Hebrew Bible. Its lemma attribute gives prefixes and
+// the Strong's number separated by slashes, as in "b/7225" or "1254 a", and
+// the word separates the same morphemes with slashes.
+func writeHebrewWords(b *bytes.Buffer, data []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var verse string
+	var position int
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "verse":
+			for _, a := range start.Attr {
+				if a.Name.Local == "osisID" {
+					verse, position = a.Value, 0
+				}
+			}
+		case "w":
+			var w struct {
+				Lemma string `xml:"lemma,attr"`
+				Text  string `xml:",chardata"`
+			}
+			if err := d.DecodeElement(&w, &start); err != nil {
+				return err
+			}
+			if verse == "" {
+				continue
+			}
+			position++
+			lemma := w.Lemma[strings.LastIndexByte(w.Lemma, '/')+1:]
+			number := strings.TrimRight(lemma, " abcdefghijklmnopqrstuvwxyz+")
+			if _, err := strconv.Atoi(number); err != nil {
+				number = ""
+			} else {
+				number = "H" + number
+			}
+			fmt.Fprintf(b, "%s\t%d\t%s\t%s\n", verse, position, strings.ReplaceAll(w.Text, "/", ""), number)
+		}
+	}
+}
+
+// usfmMarker matches, in document order, the chapter and verse markers of
+// the unfoldingWord USFM and its words, such as
+// \w Βίβλος|lemma="βίβλος" strong="G09760" x-morph="Gr,N,,,,,NFS,"\w*
+var usfmMarker = regexp.MustCompile(`\\c (\d+)|\\v (\d+)|\\w ([^|\\]+)\|[^\\]*?strong="G(\d+)"`)
+
+// writeGreekWords appends a row for each word of a USFM book of the
+// unfoldingWord Greek New Testament. Its Strong's numbers have an extra
+// trailing digit distinguishing senses, so G09760 is G976.
+func writeGreekWords(b *bytes.Buffer, book string, data []byte) error {
+	chapter, verse, position := 0, 0, 0
+	for _, m := range usfmMarker.FindAllStringSubmatch(string(data), -1) {
+		switch {
+		case m[1] != "":
+			chapter, verse = atoi(m[1]), 0
+		case m[2] != "":
+			verse, position = atoi(m[2]), 0
+		case chapter > 0 && verse > 0:
+			number := atoi(m[4])
+			if len(m[4]) == 5 {
+				number /= 10
+			}
+			position++
+			fmt.Fprintf(b, "%s.%d.%d\t%d\t%s\tG%d\n", book, chapter, verse, position, m[3], number)
+		}
+	}
+	if chapter == 0 {
+		return fmt.Errorf("no chapters found")
+	}
+	return nil
+}
+
+// atoi converts a run of digits matched by a pattern.
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
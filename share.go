@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// ShareRequest is the body of POST /api/share, naming the chapter to share.
+type ShareRequest struct {
+	Translation string `json:"translation"`
+	BookID      int    `json:"bookId"`
+	Chapter     int    `json:"chapter"`
+}
+
+// Share is returned when a share is created. URL is the path of the page to
+// pass on.
+type Share struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// newShareToken returns a random token that cannot feasibly be guessed, as
+// it is all that protects a share.
+func newShareToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// createShareHandler snapshots the user's highlights in a chapter and returns
+// a link to a read-only page of them. The snapshot is a copy, so later edits
+// to the highlights do not show up on the shared page.
+func createShareHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req ShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Translation == "" || req.BookID < 1 || req.BookID > len(metadata.Books) || req.Chapter < 1 || req.Chapter > metadata.Books[req.BookID-1].Chapters {
+		writeJSONError(w, http.StatusUnprocessableEntity, "translation, bookId and chapter must name a chapter of the Bible")
+		return
+	}
+
+	userID := userIDFromContext(r.Context())
+	rows, err := db.QueryContext(r.Context(), `SELECT `+highlightColumns+` FROM highlights
+	                       WHERE userId = ? AND translation = ? AND bookId = ? AND chapter <= ? AND endChapter >= ? AND deletedAt IS NULL ORDER BY rowid`,
+		userID, req.Translation, req.BookID, req.Chapter, req.Chapter)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	highlights := []Highlight{}
+	for rows.Next() {
+		h, err := scanHighlight(rows)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		highlights = append(highlights, h)
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	snapshot, err := json.Marshal(highlights)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to encode highlights")
+		slog.Error("Share encoding failed", "err", err)
+		return
+	}
+	token, err := newShareToken()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to create share token")
+		slog.Error("Share token generation failed", "err", err)
+		return
+	}
+
+	if _, err := db.ExecContext(r.Context(), `INSERT INTO shares (token, userId, translation, bookId, chapter, highlights, createdAt) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		token, userID, req.Translation, req.BookID, req.Chapter, string(snapshot), timestamp()); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(Share{Token: token, URL: "/share/" + token})
+}
+
+// sharedPageHandler renders the chapter captured by a share, addressed as
+// /share/{token}, as the same read-only page /print produces. No login is
+// needed; knowing the token is enough.
+func sharedPageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := strings.TrimPrefix(r.URL.Path, "/share/")
+	var translation, snapshot string
+	var bookID, chapter int
+	err := db.QueryRowContext(r.Context(), `SELECT translation, bookId, chapter, highlights FROM shares WHERE token = ?`, token).
+		Scan(&translation, &bookID, &chapter, &snapshot)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Share not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database query failed", http.StatusInternalServerError)
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	var highlights []Highlight
+	if err := json.Unmarshal([]byte(snapshot), &highlights); err != nil {
+		http.Error(w, "Failed to read share", http.StatusInternalServerError)
+		slog.Error("Share decoding failed", "err", err)
+		return
+	}
+
+	// Keep the token out of the Referer sent to any site the page links to.
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	renderPrintPage(w, r, translation, bookID, chapter, highlights)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Statements for the hottest highlight queries, prepared once at startup by
+// prepareStatements rather than on every request. Use them inside a
+// transaction through tx.Stmt.
+var (
+	insertHighlightStmt *sql.Stmt
+	selectHighlightStmt *sql.Stmt // id, userId; skips trashed highlights
+	trashHighlightStmt  *sql.Stmt // deletedAt, id, userId
+)
+
+// prepareStatements prepares the package-level statements on conn. It must run
+// after migrations, since preparing fails if the tables do not exist yet.
+func prepareStatements(conn *sql.DB) error {
+	var err error
+	if insertHighlightStmt, err = conn.Prepare(insertHighlightSQL); err != nil {
+		return err
+	}
+	if selectHighlightStmt, err = conn.Prepare(`SELECT ` + highlightColumns + ` FROM highlights WHERE id = ? AND userId = ? AND deletedAt IS NULL`); err != nil {
+		return err
+	}
+	// Trashing counts as a change, so that /api/highlights/changes reports it.
+	if trashHighlightStmt, err = conn.Prepare(`UPDATE highlights SET deletedAt = ?1, updatedAt = ?1 WHERE id = ?2 AND userId = ?3 AND deletedAt IS NULL`); err != nil {
+		return err
+	}
+	return nil
+}
+
+// closeStatements closes the statements opened by prepareStatements, ahead of
+// closing the database on shutdown.
+func closeStatements() error {
+	var errs []error
+	for _, stmt := range []*sql.Stmt{insertHighlightStmt, selectHighlightStmt, trashHighlightStmt} {
+		if stmt != nil {
+			errs = append(errs, stmt.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -3,6 +3,7 @@ document.addEventListener("DOMContentLoaded", () => {
   const bibleContent = document.getElementById("bible-content");
   const translationSelect = document.getElementById("translation-select");
   const themeToggle = document.getElementById("theme-toggle");
+  const printBtn = document.getElementById("print-button");
   const toggleNumbers = document.getElementById("toggle-numbers");
   const bookSelect = document.getElementById("book-select");
   const chapterSelect = document.getElementById("chapter-select");
@@ -13,6 +14,15 @@ document.addEventListener("DOMContentLoaded", () => {
   const saveNoteBtn = document.getElementById("save-note");
   const highlightBtn = document.getElementById("highlight-text");
 
+  const loginModal = document.getElementById("login-modal");
+  const loginUsername = document.getElementById("login-username");
+  const loginPassword = document.getElementById("login-password");
+  const loginError = document.getElementById("login-error");
+  const loginBtn = document.getElementById("login-button");
+  const registerBtn = document.getElementById("register-button");
+  const accountBtn = document.getElementById("account-button");
+  const currentUserLabel = document.getElementById("current-user");
+
   // --- State ---
   let currentSelection = null;
   let currentState = {
@@ -21,7 +31,10 @@ document.addEventListener("DOMContentLoaded", () => {
     chapter: 1,
     translation: "NASB95",
     books: [], // To store the list of books for the current translation
+    username: null, // Set once logged in; highlights require an account
   };
+  let highlightSocket = null; // Live updates for the chapter being read
+  let highlightSocketKey = "";
 
   // --- API Base URL ---
   const API_URL = "https://bolls.life";
@@ -248,6 +261,21 @@ document.addEventListener("DOMContentLoaded", () => {
     }
   }
 
+  /**
+   * Extracts the message from an API error response, which the server sends
+   * as {"error": "..."}.
+   * @param {Response} response A response whose status is not ok.
+   * @returns {Promise<string>} The error message.
+   */
+  async function readError(response) {
+    try {
+      const data = await response.json();
+      return data.error || response.statusText;
+    } catch (e) {
+      return response.statusText;
+    }
+  }
+
   // --- Highlighting and Notes ---
 
   function getRangeLocation(range) {
@@ -285,6 +313,9 @@ document.addEventListener("DOMContentLoaded", () => {
   function applyHighlightFromLocation(location) {
     const p = document.getElementById(location.verseId);
     if (!p) return;
+    // An empty range marks a verse-level highlight, such as one copied from
+    // another translation, and covers the whole verse.
+    const end = location.end > location.start ? location.end : p.textContent.length;
 
     const walker = document.createTreeWalker(p, NodeFilter.SHOW_TEXT);
     let charCount = 0;
@@ -300,9 +331,9 @@ document.addEventListener("DOMContentLoaded", () => {
         startNode = node;
         startOffset = location.start - charCount;
       }
-      if (endNode === null && location.end <= charCount + nodeLength) {
+      if (endNode === null && end <= charCount + nodeLength) {
         endNode = node;
-        endOffset = location.end - charCount;
+        endOffset = end - charCount;
         break;
       }
       charCount += nodeLength;
@@ -355,6 +386,10 @@ document.addEventListener("DOMContentLoaded", () => {
 
     try {
       const response = await fetch(url);
+      if (response.status === 401) {
+        setCurrentUser(null);
+        return;
+      }
       if (!response.ok) {
         throw new Error(`Failed to fetch highlights: ${response.statusText}`);
       }
@@ -362,11 +397,57 @@ document.addEventListener("DOMContentLoaded", () => {
       if (highlights) {
         highlights.forEach(applyHighlightFromLocation);
       }
+      connectHighlightSync();
     } catch (error) {
       console.error("Could not load highlights:", error);
     }
   }
 
+  /**
+   * Opens a WebSocket for the current chapter so highlights made on another
+   * device show up without reloading. Does nothing if one is already open
+   * for this chapter.
+   */
+  function connectHighlightSync() {
+    const { translation, bookId, chapter } = currentState;
+    const key = `translation=${translation}&bookId=${bookId}&chapter=${chapter}`;
+    if (highlightSocket && highlightSocketKey === key) return;
+    if (highlightSocket) highlightSocket.close();
+
+    const scheme = location.protocol === "https:" ? "wss" : "ws";
+    const socket = new WebSocket(
+      `${scheme}://${location.host}/ws/highlights?${key}`,
+    );
+    highlightSocket = socket;
+    highlightSocketKey = key;
+
+    socket.addEventListener("message", (e) =>
+      handleHighlightEvent(JSON.parse(e.data)),
+    );
+    socket.addEventListener("close", () => {
+      if (highlightSocket === socket) {
+        highlightSocket = null;
+        highlightSocketKey = "";
+      }
+    });
+  }
+
+  function handleHighlightEvent(event) {
+    switch (event.type) {
+      case "created":
+      case "updated":
+        unwrapHighlight(event.id);
+        applyHighlightFromLocation(event.highlight);
+        break;
+      case "deleted":
+        unwrapHighlight(event.id);
+        break;
+      case "cleared":
+        loadBibleText();
+        break;
+    }
+  }
+
   /**
    * Fetches and displays a Strong's definition by calling the local backend,
    * which in turn scrapes the Blue Letter Bible website.
@@ -415,7 +496,7 @@ document.addEventListener("DOMContentLoaded", () => {
       );
 
       if (!response.ok) {
-        const errorText = await response.text();
+        const errorText = await readError(response);
         throw new Error(
           `Failed to get definition: ${errorText} (Status: ${response.status})`,
         );
@@ -475,7 +556,9 @@ document.addEventListener("DOMContentLoaded", () => {
     }
 
     try {
-      const response = await fetch("/api/highlights", {
+      // Overlapping highlights of the same type are merged into this one;
+      // the sync socket removes the ones it replaced.
+      const response = await fetch("/api/highlights?merge=true", {
         method: "POST",
         headers: {
           "Content-Type": "application/json",
@@ -483,11 +566,18 @@ document.addEventListener("DOMContentLoaded", () => {
         body: JSON.stringify(newHighlight),
       });
 
+      if (response.status === 401) {
+        setCurrentUser(null);
+        showLoginModal();
+        return;
+      }
       if (!response.ok) {
         throw new Error(`Failed to save highlight: ${response.statusText}`);
       }
 
-      applyHighlightFromLocation(newHighlight); // Apply to DOM immediately
+      const saved = await response.json();
+      unwrapHighlight(saved.id);
+      applyHighlightFromLocation(saved); // Apply to DOM immediately
       closeAllModals();
     } catch (error) {
       console.error("Could not save highlight:", error);
@@ -506,37 +596,44 @@ document.addEventListener("DOMContentLoaded", () => {
       }
 
       // If API call is successful, remove from DOM
-      const noteSymbol = document.querySelector(
-        `.note-symbol[data-highlight-id="${id}"]`,
-      );
-      if (noteSymbol) {
-        const textSpan = document.getElementById(`note-text-${id}`);
-        if (textSpan) {
-          const parent = textSpan.parentNode;
-          while (textSpan.firstChild) {
-            parent.insertBefore(textSpan.firstChild, textSpan);
-          }
-          parent.removeChild(textSpan);
-        }
-        noteSymbol.remove();
-      } else {
-        const highlightEl = document.querySelector(
-          `.highlight-only[data-highlight-id="${id}"]`,
-        );
-        if (highlightEl) {
-          const parent = highlightEl.parentNode;
-          while (highlightEl.firstChild) {
-            parent.insertBefore(highlightEl.firstChild, highlightEl);
-          }
-          parent.removeChild(highlightEl);
-        }
-      }
+      unwrapHighlight(id);
     } catch (error) {
       console.error("Could not remove highlight:", error);
       alert("There was a problem removing your highlight. Please try again.");
     }
   }
 
+  /**
+   * Removes the markup for a highlight or note, leaving its text in place.
+   */
+  function unwrapHighlight(id) {
+    const noteSymbol = document.querySelector(
+      `.note-symbol[data-highlight-id="${id}"]`,
+    );
+    if (noteSymbol) {
+      const textSpan = document.getElementById(`note-text-${id}`);
+      if (textSpan) {
+        const parent = textSpan.parentNode;
+        while (textSpan.firstChild) {
+          parent.insertBefore(textSpan.firstChild, textSpan);
+        }
+        parent.removeChild(textSpan);
+      }
+      noteSymbol.remove();
+    } else {
+      const highlightEl = document.querySelector(
+        `.highlight-only[data-highlight-id="${id}"]`,
+      );
+      if (highlightEl) {
+        const parent = highlightEl.parentNode;
+        while (highlightEl.firstChild) {
+          parent.insertBefore(highlightEl.firstChild, highlightEl);
+        }
+        parent.removeChild(highlightEl);
+      }
+    }
+  }
+
   function showActionMenu(element) {
     closeAllModals();
 
@@ -584,6 +681,90 @@ document.addEventListener("DOMContentLoaded", () => {
     document.body.appendChild(menu);
   }
 
+  // --- Accounts ---
+
+  /**
+   * Updates the header to reflect who is logged in.
+   * @param {string|null} username The logged-in user, or null when logged out.
+   */
+  function setCurrentUser(username) {
+    currentState.username = username;
+    currentUserLabel.textContent = username ? `Signed in as ${username}` : "";
+    accountBtn.textContent = username ? "Log Out" : "Log In";
+  }
+
+  function showLoginModal() {
+    closeAllModals();
+    loginError.textContent = "";
+    loginModal.style.display = "flex";
+    loginUsername.focus();
+  }
+
+  /**
+   * Asks the server who the session cookie belongs to, if anyone.
+   */
+  async function checkSession() {
+    try {
+      const response = await fetch("/api/me");
+      if (response.ok) {
+        const data = await response.json();
+        setCurrentUser(data.username);
+      } else {
+        setCurrentUser(null);
+      }
+    } catch (error) {
+      console.error("Could not check login status:", error);
+    }
+  }
+
+  /**
+   * Logs in or registers with the credentials in the login form, then
+   * reloads the chapter so the user's highlights are shown.
+   * @param {string} path Either "/api/login" or "/api/register".
+   */
+  async function submitCredentials(path) {
+    const username = loginUsername.value.trim();
+    const password = loginPassword.value;
+    if (!username || !password) {
+      loginError.textContent = "Please enter a username and password.";
+      return;
+    }
+
+    try {
+      const response = await fetch(path, {
+        method: "POST",
+        headers: {
+          "Content-Type": "application/json",
+        },
+        body: JSON.stringify({ username, password }),
+      });
+
+      if (!response.ok) {
+        loginError.textContent = await readError(response);
+        return;
+      }
+
+      const data = await response.json();
+      setCurrentUser(data.username);
+      loginPassword.value = "";
+      closeAllModals();
+      loadBibleText();
+    } catch (error) {
+      console.error("Could not log in:", error);
+      loginError.textContent = "There was a problem logging in. Please try again.";
+    }
+  }
+
+  async function logout() {
+    try {
+      await fetch("/api/logout", { method: "POST" });
+    } catch (error) {
+      console.error("Could not log out:", error);
+    }
+    setCurrentUser(null);
+    loadBibleText(); // Re-render without the previous user's highlights
+  }
+
   /**
    * Closes all open modals.
    */
@@ -604,6 +785,22 @@ document.addEventListener("DOMContentLoaded", () => {
   // --- Event Listeners ---
 
   themeToggle.addEventListener("click", toggleTheme);
+  printBtn.addEventListener("click", () => {
+    const { translation, bookId, chapter } = currentState;
+    window.open(
+      `/print?translation=${translation}&bookId=${bookId}&chapter=${chapter}`,
+    );
+  });
+  accountBtn.addEventListener("click", () =>
+    currentState.username ? logout() : showLoginModal(),
+  );
+  loginBtn.addEventListener("click", () => submitCredentials("/api/login"));
+  registerBtn.addEventListener("click", () =>
+    submitCredentials("/api/register"),
+  );
+  loginPassword.addEventListener("keydown", (e) => {
+    if (e.key === "Enter") submitCredentials("/api/login");
+  });
   toggleNumbers.addEventListener("change", toggleVerseNumbers);
   translationSelect.addEventListener("change", loadBooks);
 
@@ -689,5 +886,6 @@ document.addEventListener("DOMContentLoaded", () => {
   // --- Initialization ---
   applySavedTheme();
   toggleVerseNumbers();
+  checkSession();
   loadBooks();
 });
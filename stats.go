@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// Stats summarizes a user's highlights for the dashboard panel.
+type Stats struct {
+	TotalHighlights int                `json:"totalHighlights"`
+	TotalVerses     int                `json:"totalVerses"`
+	ByTranslation   []TranslationCount `json:"byTranslation"`
+	ByBook          []BookCount        `json:"byBook"`
+}
+
+// TranslationCount is the number of highlights made in one translation.
+type TranslationCount struct {
+	Translation string `json:"translation"`
+	Count       int    `json:"count"`
+}
+
+// BookCount is the number of highlights made in one book, across
+// translations.
+type BookCount struct {
+	BookID   int    `json:"bookId"`
+	BookName string `json:"bookName"`
+	Count    int    `json:"count"`
+}
+
+// statsHandler returns aggregate highlight counts for the current user.
+// Verses are counted once however many highlights they carry.
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	userID := userIDFromContext(r.Context())
+
+	stats := Stats{ByTranslation: []TranslationCount{}, ByBook: []BookCount{}}
+	err := db.QueryRowContext(r.Context(), `SELECT COUNT(*), COUNT(DISTINCT verseId) FROM highlights WHERE userId = ? AND deletedAt IS NULL`, userID).
+		Scan(&stats.TotalHighlights, &stats.TotalVerses)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	rows, err := db.QueryContext(r.Context(), `SELECT translation, COUNT(*) FROM highlights WHERE userId = ? AND deletedAt IS NULL
+	                       GROUP BY translation ORDER BY translation`, userID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c TranslationCount
+		if err := rows.Scan(&c.Translation, &c.Count); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		stats.ByTranslation = append(stats.ByTranslation, c)
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	bookRows, err := db.QueryContext(r.Context(), `SELECT bookId, COUNT(*) FROM highlights WHERE userId = ? AND deletedAt IS NULL
+	                           GROUP BY bookId ORDER BY bookId`, userID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer bookRows.Close()
+	for bookRows.Next() {
+		var c BookCount
+		if err := bookRows.Scan(&c.BookID, &c.Count); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		if c.BookID >= 1 && c.BookID <= len(bookNames) {
+			c.BookName = bookNames[c.BookID-1]
+		}
+		stats.ByBook = append(stats.ByBook, c)
+	}
+	if err := bookRows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// ChapterCount is the number of highlights in one chapter of a book.
+type ChapterCount struct {
+	Chapter int `json:"chapter"`
+	Count   int `json:"count"`
+}
+
+// heatmapHandler returns the highlight count for every chapter of a book in
+// one translation, including chapters with none, for a density overview.
+func heatmapHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	translation, bookID, err := parseBookQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if bookID > len(metadata.Books) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("bookId must be between 1 and %d", len(metadata.Books)))
+		return
+	}
+
+	counts := make([]ChapterCount, metadata.Books[bookID-1].Chapters)
+	for i := range counts {
+		counts[i].Chapter = i + 1
+	}
+
+	rows, err := db.QueryContext(r.Context(), `SELECT chapter, COUNT(*) FROM highlights
+	                       WHERE userId = ? AND translation = ? AND bookId = ? AND deletedAt IS NULL
+	                       GROUP BY chapter`, userIDFromContext(r.Context()), translation, bookID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var chapter, count int
+		if err := rows.Scan(&chapter, &count); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		// Ignore chapters outside the book, which only malformed rows have.
+		if chapter >= 1 && chapter <= len(counts) {
+			counts[chapter-1].Count = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(counts)
+}
+
+// OutlineBook is one book of the canon with the number of highlights in it.
+type OutlineBook struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Chapters   int    `json:"chapters"`
+	Highlights int    `json:"highlights"`
+}
+
+// outlineHandler returns every book in canonical order with its chapter
+// count and how many highlights the user has in it in one translation, so a
+// navigation sidebar can be drawn with a single request.
+func outlineHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	translation := r.URL.Query().Get("translation")
+	if translation == "" {
+		writeJSONError(w, http.StatusBadRequest, "Missing required query parameter: translation")
+		return
+	}
+
+	books := make([]OutlineBook, len(metadata.Books))
+	for i, b := range metadata.Books {
+		books[i] = OutlineBook{ID: b.ID, Name: b.Name, Chapters: b.Chapters}
+	}
+
+	rows, err := db.QueryContext(r.Context(), `SELECT bookId, COUNT(*) FROM highlights
+	                       WHERE userId = ? AND translation = ? AND deletedAt IS NULL
+	                       GROUP BY bookId`, userIDFromContext(r.Context()), translation)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bookID, count int
+		if err := rows.Scan(&bookID, &count); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		// Books in metadata are in bookId order, starting at 1.
+		if bookID >= 1 && bookID <= len(books) {
+			books[bookID-1].Highlights = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(books)
+}
+
+// Page sizes for the top verses list.
+const (
+	defaultTopVersesLimit = 10
+	maxTopVersesLimit     = 100
+)
+
+// VerseCount is the number of highlights on one verse.
+type VerseCount struct {
+	VerseID string `json:"verseId"`
+	BookID  int    `json:"bookId"`
+	Chapter int    `json:"chapter"`
+	Count   int    `json:"count"`
+}
+
+// topVersesHandler returns the most highlighted verses across all users and
+// translations, most highlighted first. Only counts are returned, never
+// anyone's notes.
+func topVersesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	limit, _, err := parsePagination(r, defaultTopVersesLimit, maxTopVersesLimit)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rows, err := db.QueryContext(r.Context(), `SELECT verseId, bookId, chapter, COUNT(*) AS n FROM highlights
+	                       WHERE deletedAt IS NULL
+	                       GROUP BY verseId, bookId, chapter
+	                       ORDER BY n DESC, bookId, chapter, `+verseNumberSQL+`
+	                       LIMIT ?`, limit)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	verses := []VerseCount{}
+	for rows.Next() {
+		var v VerseCount
+		if err := rows.Scan(&v.VerseID, &v.BookID, &v.Chapter, &v.Count); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		verses = append(verses, v)
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verses)
+}
@@ -0,0 +1,644 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// StrongsDefinition holds the scraped definition data.
+type StrongsDefinition struct {
+	StrongsNumber   string `json:"strongsNumber"`
+	Lexeme          string `json:"lexeme"`
+	Transliteration string `json:"transliteration"`
+	Definition      string `json:"definition"`
+	DefinitionHTML  string `json:"definitionHtml"` // Definition with its paragraphs and emphasis, sanitized
+}
+
+// VerseRef identifies the verse a word is being looked up in, using the same
+// strings the frontend sends.
+type VerseRef struct {
+	Translation string
+	BookName    string
+	Chapter     string
+	Verse       string
+}
+
+// StrongsProvider resolves a word in a verse to its Strong's definition.
+type StrongsProvider interface {
+	Lookup(ctx context.Context, word string, ref VerseRef) (StrongsDefinition, error)
+}
+
+// StrongsNumberProvider is implemented by providers that can also look a
+// definition up by its Strong's number alone.
+type StrongsNumberProvider interface {
+	LookupNumber(ctx context.Context, number string) (StrongsDefinition, error)
+}
+
+// lookupError is returned by providers so the handler can answer with the
+// status code and message that describe the failure.
+type lookupError struct {
+	Status  int
+	Message string
+}
+
+func (e *lookupError) Error() string {
+	return e.Message
+}
+
+// strongsProviders are tried in order until one succeeds. The offline
+// lexicon is appended at startup when its data file is available.
+var strongsProviders = []StrongsProvider{blbProvider{}}
+
+// userAgent identifies this app to the sites it fetches from.
+const userAgent = "bible_app/1.0 (+https://github.com/SeeSharpSi/bible)"
+
+// Fetcher performs a single outbound GET request. Lookups go through the
+// package-level fetcher, so tests can swap in one that serves fixture pages.
+type Fetcher interface {
+	Get(ctx context.Context, url string) (*http.Response, error)
+}
+
+// httpFetcher is the real Fetcher, sending userAgent with every request.
+type httpFetcher struct {
+	client *http.Client
+}
+
+func (f httpFetcher) Get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	return f.client.Do(req)
+}
+
+// fetchTimeout keeps a hung upstream connection from tying up a handler
+// goroutine indefinitely.
+const fetchTimeout = 10 * time.Second
+
+// fetcher is used for all outbound requests. Its client uses the default
+// transport, which honours HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+var fetcher Fetcher = httpFetcher{&http.Client{Timeout: fetchTimeout}}
+
+// proxyFetcher returns a Fetcher that sends every request through proxyURL,
+// overriding the proxy environment variables.
+func proxyFetcher(proxyURL string) (Fetcher, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q needs a scheme and host, e.g. http://proxy:3128", proxyURL)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(u)
+	return httpFetcher{&http.Client{Timeout: fetchTimeout, Transport: transport}}, nil
+}
+
+// fetchAttempts is how many times fetch tries a request before giving up, and
+// fetchBaseDelay is the wait before the first retry; it doubles after each
+// further failure.
+const (
+	fetchAttempts  = 3
+	fetchBaseDelay = 250 * time.Millisecond
+)
+
+// fetch issues a GET request for rawURL with fetcher.
+// Network errors and 5xx responses are retried with exponential backoff; a
+// 4xx response is returned as is, since asking again will not change it. The
+// last response or error is returned once the attempts run out.
+func fetch(ctx context.Context, rawURL string) (*http.Response, error) {
+	delay := fetchBaseDelay
+	for attempt := 1; ; attempt++ {
+		res, err := fetcher.Get(ctx, rawURL)
+		if attempt == fetchAttempts || (err == nil && res.StatusCode < 500) {
+			return res, err
+		}
+		if err == nil {
+			res.Body.Close()
+		}
+		slog.Debug("Retrying fetch", "url", rawURL, "attempt", attempt, "delay", delay)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// stripMarks decomposes text and drops combining marks, so accented Greek and
+// pointed Hebrew compare equal to their bare letters.
+var stripMarks = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// normalizeWord prepares a word for loose comparison: diacritics are removed,
+// case is folded, and Greek final sigma is treated as a regular sigma.
+func normalizeWord(s string) string {
+	stripped, _, err := transform.String(stripMarks, s)
+	if err != nil {
+		stripped = s
+	}
+	return strings.ReplaceAll(strings.ToLower(stripped), "ς", "σ")
+}
+
+// trimPunct strips leading and trailing characters that are not letters or
+// digits, such as the period in "men." or the quotes around a spoken word.
+func trimPunct(s string) string {
+	return strings.TrimFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// containsWord reports whether text contains word as a whole word, comparing
+// normalized whitespace-separated tokens with surrounding punctuation removed.
+// Unlike a substring match, "son" does not match "person" or "Samson".
+func containsWord(text, word string) bool {
+	want := trimPunct(normalizeWord(word))
+	if want == "" {
+		return false
+	}
+	for _, token := range strings.Fields(normalizeWord(text)) {
+		if trimPunct(token) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// strongsDefinitionHandler looks up the Strong's definition of a word in a
+// verse, trying each of strongsProviders in turn. Results are cached in
+// SQLite; pass refresh=true to bypass the cache.
+func strongsDefinitionHandler(w http.ResponseWriter, r *http.Request) {
+	// 1. Get query parameters
+	word := r.URL.Query().Get("word")
+	ref, err := parseVerseRef(r)
+	if err == nil && word == "" {
+		err = errors.New("Missing required query parameters")
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("debug") == "true" {
+		strongsDebugHandler(w, r, word, ref)
+		return
+	}
+
+	// 2. Look the word up and send the response
+	def, err := lookupStrongs(r.Context(), word, ref, r.URL.Query().Get("refresh") == "true")
+	var le *lookupError
+	if errors.As(err, &le) {
+		writeJSONError(w, le.Status, le.Message)
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Strong's lookup failed")
+		slog.Error("Strong's lookup failed", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(def)
+}
+
+// lookupStrongs resolves a word in a verse to its definition, serving it from
+// the SQLite cache unless refresh is set and otherwise trying each of
+// strongsProviders in turn. When none of them succeed, the first failure is
+// returned; it comes from the primary source and is the most useful to the
+// client.
+func lookupStrongs(ctx context.Context, word string, ref VerseRef, refresh bool) (StrongsDefinition, error) {
+	if !refresh {
+		cached, found, err := lookupCachedStrongs(ctx, word, ref.Translation, ref.BookName, ref.Chapter, ref.Verse)
+		if err != nil {
+			slog.Error("Strong's cache lookup failed", "err", err)
+		} else if found {
+			slog.Debug("Strong's cache hit", "word", word, "book", ref.BookName, "chapter", ref.Chapter, "verse", ref.Verse)
+			return cached, nil
+		}
+	}
+
+	var firstErr error
+	for _, provider := range strongsProviders {
+		var def StrongsDefinition
+		var err error
+		blb, scraped := provider.(blbProvider)
+		if scraped && !refresh {
+			def, err = blb.lookupReusingCache(ctx, word, ref)
+		} else {
+			def, err = provider.Lookup(ctx, word, ref)
+		}
+		if scraped {
+			metrics.observeScrape(err)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		// Only scraped results are cached; the offline lexicon guesses, and
+		// a guess should not stop later requests from reaching Blue Letter
+		// Bible.
+		if scraped && def.StrongsNumber != "" {
+			if err := cacheStrongsDefinition(ctx, def, word, ref.Translation, ref.BookName, ref.Chapter, ref.Verse); err != nil {
+				slog.Error("Strong's cache write failed", "err", err)
+			}
+		}
+		return def, nil
+	}
+	return StrongsDefinition{}, firstErr
+}
+
+// parseVerseRef reads the translation, bookName, chapter and verse query
+// parameters of a Strong's lookup. The error is suitable to send back to the
+// client.
+func parseVerseRef(r *http.Request) (VerseRef, error) {
+	ref := VerseRef{
+		Translation: r.URL.Query().Get("translation"),
+		BookName:    r.URL.Query().Get("bookName"),
+		Chapter:     r.URL.Query().Get("chapter"),
+		Verse:       r.URL.Query().Get("verse"),
+	}
+	if ref.Translation == "" || ref.BookName == "" || ref.Chapter == "" || ref.Verse == "" {
+		return ref, errors.New("Missing required query parameters")
+	}
+	return ref, ref.checkNumbers()
+}
+
+// checkNumbers reports a chapter or verse that is not a positive integer.
+// The error is suitable to send back to the client.
+func (ref VerseRef) checkNumbers() error {
+	if n, err := strconv.Atoi(ref.Chapter); err != nil || n < 1 {
+		return errors.New("chapter must be a positive integer")
+	}
+	if n, err := strconv.Atoi(ref.Verse); err != nil || n < 1 {
+		return errors.New("verse must be a positive integer")
+	}
+	return nil
+}
+
+// StrongsDebug reports the Blue Letter Bible URLs a lookup goes through, for
+// working out why one fails. Error is the reason DefinitionURL could not be
+// found, if it was not.
+type StrongsDebug struct {
+	SearchURL     string `json:"searchUrl"`
+	DefinitionURL string `json:"definitionUrl,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// strongsDebugHandler answers a debug=true lookup: it fetches the search page
+// to find the definition URL, bypassing the cache, but stops short of
+// fetching and parsing the definition itself.
+func strongsDebugHandler(w http.ResponseWriter, r *http.Request, word string, ref VerseRef) {
+	debug := StrongsDebug{SearchURL: blbSearchURL(word, ref)}
+	definitionURL, err := findDefinitionURL(r.Context(), word, ref)
+	if err != nil {
+		debug.Error = err.Error()
+	}
+	debug.DefinitionURL = definitionURL
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(debug)
+}
+
+// blbProvider scrapes Blue Letter Bible for a Strong's definition.
+// It is brittle and depends on the HTML structure of blueletterbible.org.
+type blbProvider struct{}
+
+func (blbProvider) Lookup(ctx context.Context, word string, ref VerseRef) (StrongsDefinition, error) {
+	definitionURL, err := findDefinitionURL(ctx, word, ref)
+	if err != nil {
+		return StrongsDefinition{}, err
+	}
+
+	// Make the second request to the definition page
+	return scrapeDefinitionPage(ctx, definitionURL)
+}
+
+// LookupNumber scrapes the lexicon page of number as Blue Letter Bible links
+// it from the Westminster Leningrad Codex for Hebrew numbers and from the
+// Textus Receptus for Greek ones.
+func (blbProvider) LookupNumber(ctx context.Context, number string) (StrongsDefinition, error) {
+	text := "tr"
+	if strings.HasPrefix(number, "H") {
+		text = "wlc"
+	}
+	return scrapeDefinitionPage(ctx, fmt.Sprintf("https://www.blueletterbible.org/lexicon/%s/kjv/%s/0-1/", strings.ToLower(number), text))
+}
+
+// lookupReusingCache is Lookup, except that when the word resolves to a
+// Strong's number already in the SQLite cache that definition is returned
+// instead of fetching its lexicon page again. Many words in a chapter share
+// a number, so this saves most of the requests when preloading one.
+func (p blbProvider) lookupReusingCache(ctx context.Context, word string, ref VerseRef) (StrongsDefinition, error) {
+	definitionURL, err := findDefinitionURL(ctx, word, ref)
+	if err != nil {
+		return StrongsDefinition{}, err
+	}
+	if m := lexiconLinkPattern.FindStringSubmatch(definitionURL); m != nil {
+		number, _ := normalizeStrongsNumber(m[1])
+		def, found, err := lookupCachedStrongsNumber(ctx, number)
+		if err != nil {
+			slog.Error("Strong's cache lookup failed", "err", err)
+		} else if found {
+			return def, nil
+		}
+	}
+	return scrapeDefinitionPage(ctx, definitionURL)
+}
+
+// blbSearchURL builds the URL of Blue Letter Bible's interlinear view of the
+// verse, searched for word.
+func blbSearchURL(word string, ref VerseRef) string {
+	verseRef := fmt.Sprintf("%s+%s:%s", ref.BookName, ref.Chapter, ref.Verse)
+	// Note: The 'Criteria' is the word we are looking for. 'fromverse' gives it context.
+	return fmt.Sprintf("https://www.blueletterbible.org/search/preSearch.cfm?Criteria=%s&t=%s&ss=1&source=from_interlinear&fromverse=%s", url.QueryEscape(word), ref.Translation, url.QueryEscape(verseRef))
+}
+
+// findDefinitionURL fetches the interlinear view of the verse and returns the
+// link to the lexicon page of the Strong's number given for word.
+func findDefinitionURL(ctx context.Context, word string, ref VerseRef) (string, error) {
+	// 1. Construct the search URL for Blue Letter Bible's interlinear view
+	searchURL := blbSearchURL(word, ref)
+
+	// 2. Make the first request to get the interlinear page and find the Strong's link
+	doc, err := fetchInterlinear(ctx, searchURL)
+	if err != nil {
+		return "", err
+	}
+
+	// 3. Find the link to the Strong's definition.
+	var definitionURL string
+	doc.Find("td.calque-processed").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if containsWord(s.Text(), word) {
+			// Found the word, now find the Strong's link in the same row (parent tr).
+			link, found := s.Parent().Find("td.strongs-num-unprocessed a").Attr("href")
+			if found {
+				definitionURL = "https://www.blueletterbible.org" + link
+				return false // Stop iterating
+			}
+		}
+		return true // Continue iterating
+	})
+
+	if definitionURL == "" {
+		slog.Warn("Could not find Strong's link", "word", word, "url", searchURL)
+		return "", &lookupError{http.StatusNotFound, "Could not find Strong's number link on Blue Letter Bible. The site's structure may have changed, or the word was not found in the interlinear view for that verse."}
+	}
+	return definitionURL, nil
+}
+
+// fetchInterlinear fetches and parses an interlinear page from searchURL,
+// returning a lookupError on failure.
+func fetchInterlinear(ctx context.Context, searchURL string) (*goquery.Document, error) {
+	res, err := fetchPage(ctx, searchURL)
+	if err != nil {
+		slog.Error("BLB request failed", "err", err, "url", searchURL)
+		return nil, &lookupError{http.StatusInternalServerError, "Failed to fetch from Blue Letter Bible"}
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		slog.Error("BLB returned an error status", "status", res.StatusCode, "url", searchURL)
+		return nil, &lookupError{http.StatusBadGateway, fmt.Sprintf("Blue Letter Bible returned non-200 status: %d", res.StatusCode)}
+	}
+
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		slog.Error("BLB page parsing failed", "err", err)
+		return nil, &lookupError{http.StatusInternalServerError, "Failed to parse BLB response"}
+	}
+	return doc, nil
+}
+
+// VerseWord is one word of a verse in the interlinear view, with the
+// Strong's number it translates. Words the translators supplied have no
+// number. Lexeme comes from the offline lexicon and is empty without it.
+type VerseWord struct {
+	Word          string `json:"word"`
+	StrongsNumber string `json:"strongsNumber,omitempty"`
+	Lexeme        string `json:"lexeme,omitempty"`
+}
+
+// lexiconLinkPattern extracts the Strong's number from a lexicon link such
+// as /lexicon/g2316/kjv/tr/0-1/.
+var lexiconLinkPattern = regexp.MustCompile(`/lexicon/([gGhH][0-9]+)/`)
+
+// strongsVerseHandler returns every word of a verse with its Strong's
+// number, read from a single interlinear page instead of one lookup per
+// word.
+func strongsVerseHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ref, err := parseVerseRef(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The interlinear view lists the whole verse; the search criteria only
+	// pick which word the page starts at, so none is given.
+	doc, err := fetchInterlinear(r.Context(), blbSearchURL("", ref))
+	metrics.observeScrape(err)
+	var le *lookupError
+	if errors.As(err, &le) {
+		writeJSONError(w, le.Status, le.Message)
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Strong's lookup failed")
+		slog.Error("Strong's lookup failed", "err", err)
+		return
+	}
+
+	words := []VerseWord{}
+	doc.Find("td.calque-processed").Each(func(i int, s *goquery.Selection) {
+		vw := VerseWord{Word: strings.TrimSpace(s.Text())}
+		if vw.Word == "" {
+			return
+		}
+		link, _ := s.Parent().Find("td.strongs-num-unprocessed a").Attr("href")
+		if m := lexiconLinkPattern.FindStringSubmatch(link); m != nil {
+			vw.StrongsNumber, _ = normalizeStrongsNumber(m[1])
+		}
+		if offlineLexicon != nil {
+			vw.Lexeme = offlineLexicon.entries[vw.StrongsNumber].Lemma
+		}
+		words = append(words, vw)
+	})
+	if len(words) == 0 {
+		slog.Warn("No words found in interlinear view", "book", ref.BookName, "chapter", ref.Chapter, "verse", ref.Verse)
+		writeJSONError(w, http.StatusNotFound, "Could not find the verse's words on Blue Letter Bible. The site's structure may have changed, or the verse does not exist.")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(words)
+}
+
+// scrapeDefinitionPage fetches a Blue Letter Bible lexicon page and scrapes
+// the definition details from it.
+func scrapeDefinitionPage(ctx context.Context, definitionURL string) (StrongsDefinition, error) {
+	defRes, err := fetchPage(ctx, definitionURL)
+	if err != nil {
+		slog.Error("BLB definition page request failed", "err", err)
+		return StrongsDefinition{}, &lookupError{http.StatusInternalServerError, "Failed to fetch definition page from BLB"}
+	}
+	defer defRes.Body.Close()
+
+	if defRes.StatusCode != 200 {
+		return StrongsDefinition{}, &lookupError{http.StatusBadGateway, fmt.Sprintf("BLB definition page returned non-200 status: %d", defRes.StatusCode)}
+	}
+
+	defDoc, err := goquery.NewDocumentFromReader(defRes.Body)
+	if err != nil {
+		slog.Error("BLB definition page parsing failed", "err", err)
+		return StrongsDefinition{}, &lookupError{http.StatusInternalServerError, "Failed to parse BLB definition response"}
+	}
+
+	// Scrape the definition details from the lexicon page.
+	strongsNumber := defDoc.Find("#lexicon-head h1").Text()
+	lexeme := defDoc.Find(".lex-lemma-head .lexeme").First().Text()
+	transliteration := defDoc.Find(".lex-lemma-head .translit").First().Text()
+
+	var definitionBuilder, htmlBuilder strings.Builder
+	defDoc.Find("#lexDef p").Each(func(i int, s *goquery.Selection) {
+		definitionBuilder.WriteString(s.Text())
+		definitionBuilder.WriteString("\n\n") // Add paragraphs for readability
+		if inner, err := s.Html(); err == nil {
+			if inner = sanitizeDefinitionHTML(inner); inner != "" {
+				htmlBuilder.WriteString("<p>" + inner + "</p>")
+			}
+		}
+	})
+
+	definition := strings.TrimSpace(definitionBuilder.String())
+	definitionHTML := htmlBuilder.String()
+	if definition == "" {
+		// Fallback for different structures (sometimes content is not in 'p' tags)
+		lexDef := defDoc.Find("#lexDef").First()
+		definition = strings.TrimSpace(lexDef.Text())
+		definitionHTML = ""
+		if inner, err := lexDef.Html(); err == nil {
+			definitionHTML = sanitizeDefinitionHTML(inner)
+		}
+	}
+
+	return StrongsDefinition{
+		StrongsNumber:   strings.TrimSpace(strongsNumber),
+		Lexeme:          strings.TrimSpace(lexeme),
+		Transliteration: strings.TrimSpace(transliteration),
+		Definition:      definition,
+		DefinitionHTML:  definitionHTML,
+	}, nil
+}
+
+// strongsNumberPattern matches a Strong's number such as G26 or H430.
+var strongsNumberPattern = regexp.MustCompile(`^[GH][0-9]{1,5}$`)
+
+const invalidStrongsNumberMessage = "Strong's number must be G or H followed by digits, e.g. G26"
+
+// normalizeStrongsNumber uppercases a Strong's number and reports whether it
+// is well formed. An empty number is allowed and left empty.
+func normalizeStrongsNumber(number string) (string, bool) {
+	number = strings.ToUpper(strings.TrimSpace(number))
+	return number, number == "" || strongsNumberPattern.MatchString(number)
+}
+
+// strongsNumberHandler returns the definition for a Strong's number given in
+// the path as /api/strongs/{number}. Since the number is already known, the
+// interlinear search is skipped: the definition comes from the SQLite cache
+// unless refresh=true is passed, and otherwise from the first of
+// strongsProviders that can look a number up.
+func strongsNumberHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	number, ok := normalizeStrongsNumber(strings.TrimPrefix(r.URL.Path, "/api/strongs/"))
+	if !ok || number == "" {
+		writeJSONError(w, http.StatusBadRequest, invalidStrongsNumberMessage)
+		return
+	}
+
+	def, err := lookupStrongsNumber(r.Context(), number, r.URL.Query().Get("refresh") == "true")
+	var le *lookupError
+	if errors.As(err, &le) {
+		writeJSONError(w, le.Status, le.Message)
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Strong's lookup failed")
+		slog.Error("Strong's lookup failed", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(def)
+}
+
+// lookupStrongsNumber is lookupStrongs for a known Strong's number, trying
+// the providers that implement StrongsNumberProvider. As there, only scraped
+// definitions are cached and the first failure is returned.
+func lookupStrongsNumber(ctx context.Context, number string, refresh bool) (StrongsDefinition, error) {
+	if !refresh {
+		cached, found, err := lookupCachedStrongsNumber(ctx, number)
+		if err != nil {
+			slog.Error("Strong's cache lookup failed", "err", err)
+		} else if found {
+			return cached, nil
+		}
+	}
+
+	var firstErr error
+	for _, provider := range strongsProviders {
+		byNumber, ok := provider.(StrongsNumberProvider)
+		if !ok {
+			continue
+		}
+		def, err := byNumber.LookupNumber(ctx, number)
+		_, scraped := provider.(blbProvider)
+		if scraped {
+			metrics.observeScrape(err)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		if def.StrongsNumber == "" {
+			def.StrongsNumber = number
+		}
+		if scraped {
+			if err := cacheStrongsNumber(ctx, def); err != nil {
+				slog.Error("Strong's cache write failed", "err", err)
+			}
+		}
+		return def, nil
+	}
+	return StrongsDefinition{}, firstErr
+}
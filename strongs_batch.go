@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxStrongsBatchItems bounds a batch lookup; it is enough for every word of
+// the longest chapters.
+const maxStrongsBatchItems = 2000
+
+// maxStrongsBatchBodyBytes bounds the body of a batch lookup, leaving room for
+// maxStrongsBatchItems items with long book and translation names.
+const maxStrongsBatchBodyBytes = 512 << 10
+
+// strongsBatchSlots limits how many batch lookups run at once across all
+// requests, so preloading chapters does not hammer Blue Letter Bible.
+var strongsBatchSlots = make(chan struct{}, 3)
+
+// StrongsBatchItem is one word to look up in a batch. Chapter and verse
+// may be given as numbers or strings.
+type StrongsBatchItem struct {
+	Word      string `json:"word"`
+	Reference struct {
+		Translation string      `json:"translation"`
+		BookName    string      `json:"bookName"`
+		Chapter     json.Number `json:"chapter"`
+		Verse       json.Number `json:"verse"`
+	} `json:"reference"`
+}
+
+// StrongsBatchResult is the outcome of one item of a batch lookup, at the
+// same index as the item in the request. Exactly one of Definition and Error
+// is set; Status is the code a single lookup would have answered with.
+type StrongsBatchResult struct {
+	Definition *StrongsDefinition `json:"definition,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	Status     int                `json:"status"`
+}
+
+// strongsBatchHandler looks up many words at once, for preloading a chapter.
+// Items are looked up through the same cache and providers as
+// strongsDefinitionHandler, at most cap(strongsBatchSlots) at a time, and an
+// item that fails gets an error in its result rather than failing the batch.
+// Repeated items are only looked up once.
+//
+// The route has its own rate limit, charged once per batch, so a whole
+// chapter can be preloaded; strongsBatchSlots is what keeps the scraping gentle.
+func strongsBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var items []StrongsBatchItem
+	r.Body = http.MaxBytesReader(w, r.Body, maxStrongsBatchBodyBytes)
+	err := json.NewDecoder(r.Body).Decode(&items)
+	if isBodyTooLarge(err) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, bodyTooLargeMessage(maxStrongsBatchBodyBytes))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body: expected a JSON array of {word, reference}")
+		return
+	}
+	if len(items) > maxStrongsBatchItems {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("A batch may contain at most %d items", maxStrongsBatchItems))
+		return
+	}
+
+	type lookup struct {
+		word string
+		ref  VerseRef
+	}
+	results := make([]StrongsBatchResult, len(items))
+	pending := map[lookup][]int{} // Indexes of the items asking for each lookup
+	for i, item := range items {
+		l := lookup{item.Word, VerseRef{
+			Translation: item.Reference.Translation,
+			BookName:    item.Reference.BookName,
+			Chapter:     item.Reference.Chapter.String(),
+			Verse:       item.Reference.Verse.String(),
+		}}
+		if l.word == "" || l.ref.Translation == "" || l.ref.BookName == "" || l.ref.Chapter == "" || l.ref.Verse == "" {
+			results[i] = StrongsBatchResult{Error: "word, translation, bookName, chapter and verse are required", Status: http.StatusBadRequest}
+			continue
+		}
+		if err := l.ref.checkNumbers(); err != nil {
+			results[i] = StrongsBatchResult{Error: err.Error(), Status: http.StatusBadRequest}
+			continue
+		}
+		pending[l] = append(pending[l], i)
+	}
+
+	// A large batch waits its turn for strongsBatchSlots and can take far
+	// longer than -write-timeout allows; the lookups stop when the client
+	// disconnects instead.
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	var wg sync.WaitGroup
+	for l, indexes := range pending {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each lookup writes only its own indexes, so no lock is needed.
+			setResult := func(result StrongsBatchResult) {
+				for _, i := range indexes {
+					results[i] = result
+				}
+			}
+
+			select {
+			case strongsBatchSlots <- struct{}{}:
+				defer func() { <-strongsBatchSlots }()
+			case <-r.Context().Done():
+				return // The client has gone; nobody will read the results
+			}
+
+			result := StrongsBatchResult{Status: http.StatusOK}
+			def, err := lookupStrongs(r.Context(), l.word, l.ref, false)
+			var le *lookupError
+			switch {
+			case errors.As(err, &le):
+				result = StrongsBatchResult{Error: le.Message, Status: le.Status}
+			case err != nil:
+				result = StrongsBatchResult{Error: "Strong's lookup failed", Status: http.StatusInternalServerError}
+				slog.Error("Strong's lookup failed", "err", err)
+			default:
+				result.Definition = &def
+			}
+			setResult(result)
+		}()
+	}
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
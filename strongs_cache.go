@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// lookupCachedStrongs returns the cached definition for a word in a verse.
+// The boolean is false on a cache miss. Entries cached before definitions
+// were kept as HTML get paragraphs made from their plain text.
+func lookupCachedStrongs(ctx context.Context, word, translation, bookName, chapter, verse string) (StrongsDefinition, bool, error) {
+	query := `SELECT c.strongsNumber, c.lexeme, c.transliteration, c.definition, c.definitionHtml
+	          FROM strongs_lookups l JOIN strongs_cache c ON c.strongsNumber = l.strongsNumber
+	          WHERE l.word = ? AND l.translation = ? AND l.bookName = ? AND l.chapter = ? AND l.verse = ?`
+
+	var def StrongsDefinition
+	err := db.QueryRowContext(ctx, query, strings.ToLower(word), translation, bookName, chapter, verse).
+		Scan(&def.StrongsNumber, &def.Lexeme, &def.Transliteration, &def.Definition, &def.DefinitionHTML)
+	if err == sql.ErrNoRows {
+		return def, false, nil
+	}
+	if err != nil {
+		return def, false, err
+	}
+	if def.DefinitionHTML == "" {
+		def.DefinitionHTML = textDefinitionHTML(def.Definition)
+	}
+	return def, true, nil
+}
+
+// lookupCachedStrongsNumber returns the cached definition of a Strong's
+// number however it was first looked up. The boolean is false on a cache
+// miss.
+func lookupCachedStrongsNumber(ctx context.Context, number string) (StrongsDefinition, bool, error) {
+	query := `SELECT strongsNumber, lexeme, transliteration, definition, definitionHtml
+	          FROM strongs_cache WHERE upper(strongsNumber) = ?`
+
+	var def StrongsDefinition
+	err := db.QueryRowContext(ctx, query, number).
+		Scan(&def.StrongsNumber, &def.Lexeme, &def.Transliteration, &def.Definition, &def.DefinitionHTML)
+	if err == sql.ErrNoRows {
+		return def, false, nil
+	}
+	if err != nil {
+		return def, false, err
+	}
+	if def.DefinitionHTML == "" {
+		def.DefinitionHTML = textDefinitionHTML(def.Definition)
+	}
+	return def, true, nil
+}
+
+// insertStrongsCacheSQL stores a definition under its Strong's number,
+// replacing any previous entry.
+const insertStrongsCacheSQL = `INSERT OR REPLACE INTO strongs_cache (strongsNumber, lexeme, transliteration, definition, definitionHtml) VALUES (?, ?, ?, ?, ?)`
+
+// cacheStrongsNumber stores a scraped definition that was looked up by its
+// Strong's number alone, so no word or verse leads to it.
+func cacheStrongsNumber(ctx context.Context, def StrongsDefinition) error {
+	_, err := db.ExecContext(ctx, insertStrongsCacheSQL, def.StrongsNumber, def.Lexeme, def.Transliteration, def.Definition, def.DefinitionHTML)
+	return err
+}
+
+// cacheStrongsDefinition stores a scraped definition along with the word and
+// verse it was looked up from, replacing any previous entry.
+func cacheStrongsDefinition(ctx context.Context, def StrongsDefinition, word, translation, bookName, chapter, verse string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	_, err = tx.ExecContext(ctx, insertStrongsCacheSQL, def.StrongsNumber, def.Lexeme, def.Transliteration, def.Definition, def.DefinitionHTML)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT OR REPLACE INTO strongs_lookups (word, translation, bookName, chapter, verse, strongsNumber) VALUES (?, ?, ?, ?, ?, ?)`,
+		strings.ToLower(word), translation, bookName, chapter, verse, def.StrongsNumber)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
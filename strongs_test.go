@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchersUseTimeout(t *testing.T) {
+	proxied, err := proxyFetcher("http://proxy.example:3128")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, f := range map[string]Fetcher{"default": fetcher, "proxy": proxied} {
+		hf, ok := f.(httpFetcher)
+		if !ok {
+			t.Fatalf("%s fetcher is a %T, not an httpFetcher", name, f)
+		}
+		if hf.client.Timeout != fetchTimeout {
+			t.Errorf("%s fetcher timeout = %v, want %v", name, hf.client.Timeout, fetchTimeout)
+		}
+	}
+}
+
+func TestHTTPFetcherSendsUserAgent(t *testing.T) {
+	got := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Get("User-Agent")
+	}))
+	defer srv.Close()
+
+	res, err := httpFetcher{&http.Client{Timeout: fetchTimeout}}.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if ua := <-got; ua != userAgent {
+		t.Errorf("User-Agent = %q, want %q", ua, userAgent)
+	}
+}
+
+func TestHTTPFetcherGivesUpOnHungServer(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	const timeout = 100 * time.Millisecond
+	start := time.Now()
+	_, err := httpFetcher{&http.Client{Timeout: timeout}}.Get(context.Background(), srv.URL)
+	if err == nil {
+		t.Fatal("request to a hung server succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 10*timeout {
+		t.Errorf("request took %v to time out, want about %v", elapsed, timeout)
+	}
+}
+
+func TestContainsWord(t *testing.T) {
+	tests := []struct {
+		text, word string
+		want       bool
+	}{
+		{"men.", "men", true},
+		{"the son", "son", true},
+		{"“Son”", "son", true},
+		{"person", "son", false},
+		{"Samson", "son", false},
+		{"sons", "son", false},
+		{"ἀγάπης", "αγαπησ", true},
+		{"anything", "", false},
+		{"...", ".", false},
+	}
+	for _, tt := range tests {
+		if got := containsWord(tt.text, tt.word); got != tt.want {
+			t.Errorf("containsWord(%q, %q) = %v, want %v", tt.text, tt.word, got, tt.want)
+		}
+	}
+}
+
+// interlinearFixture mimics the rows of Blue Letter Bible's interlinear view:
+// the English rendering in a calque cell and the Strong's link beside it.
+const interlinearFixture = `<html><body><table>
+<tr><td class="calque-processed">a person</td><td class="strongs-num-unprocessed"><a href="/lexicon/g444/kjv/tr/0-1/">G444</a></td></tr>
+<tr><td class="calque-processed">Samson</td><td class="strongs-num-unprocessed"><a href="/lexicon/g4546/kjv/tr/0-1/">G4546</a></td></tr>
+<tr><td class="calque-processed">of men.</td><td class="strongs-num-unprocessed"><a href="/lexicon/g435/kjv/tr/0-1/">G435</a></td></tr>
+<tr><td class="calque-processed">the Son</td><td class="strongs-num-unprocessed"><a href="/lexicon/g5207/kjv/tr/0-1/">G5207</a></td></tr>
+</table></body></html>`
+
+func TestFindDefinitionURLMatchesWholeWords(t *testing.T) {
+	useFetcher(t, fetcherFunc(func(ctx context.Context, url string) (*http.Response, error) {
+		return htmlResponse(interlinearFixture), nil
+	}))
+	ref := VerseRef{Translation: "KJV", BookName: "Matthew", Chapter: "1", Verse: "1"}
+
+	tests := []struct {
+		word string
+		want string // "" for not found
+	}{
+		{"son", "https://www.blueletterbible.org/lexicon/g5207/kjv/tr/0-1/"},
+		{"men", "https://www.blueletterbible.org/lexicon/g435/kjv/tr/0-1/"},
+		{"person", "https://www.blueletterbible.org/lexicon/g444/kjv/tr/0-1/"},
+		{"per", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			got, err := findDefinitionURL(context.Background(), tt.word, ref)
+			if tt.want == "" {
+				var le *lookupError
+				if !errors.As(err, &le) || le.Status != http.StatusNotFound {
+					t.Errorf("got %q, %v; want a 404 lookupError", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStrongsDefinitionRejectsNonNumbers(t *testing.T) {
+	useFetcher(t, fetcherFunc(func(ctx context.Context, url string) (*http.Response, error) {
+		t.Errorf("fetched %s for an invalid reference", url)
+		return nil, errors.New("unreachable")
+	}))
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"chapter word", "chapter=one&verse=1", "chapter must be a positive integer"},
+		{"chapter injection", "chapter=1%27%20OR%20%271%27=%271&verse=1", "chapter must be a positive integer"},
+		{"chapter zero", "chapter=0&verse=1", "chapter must be a positive integer"},
+		{"verse word", "chapter=1&verse=first", "verse must be a positive integer"},
+		{"verse float", "chapter=1&verse=1.5", "verse must be a positive integer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/strongs_definition?word=love&translation=KJV&bookName=John&"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			strongsDefinitionHandler(rec, req)
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("got %d %s, want 400 %q", rec.Code, rec.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchRetriesTransientFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int // responses with status before a 200
+		status   int
+		wantHits int32
+		wantCode int
+	}{
+		{"succeeds first time", 0, 0, 1, http.StatusOK},
+		{"recovers after two 5xx", 2, http.StatusServiceUnavailable, 3, http.StatusOK},
+		{"gives up after three 5xx", 3, http.StatusBadGateway, fetchAttempts, http.StatusBadGateway},
+		{"does not retry a 4xx", 3, http.StatusNotFound, 1, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if int(hits.Add(1)) <= tt.failures {
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.Write([]byte("ok"))
+			}))
+			defer srv.Close()
+			useFetcher(t, httpFetcher{srv.Client()})
+
+			res, err := fetch(context.Background(), srv.URL)
+			if err != nil {
+				t.Fatalf("fetch: %v", err)
+			}
+			res.Body.Close()
+			if res.StatusCode != tt.wantCode || hits.Load() != tt.wantHits {
+				t.Errorf("got %d after %d requests, want %d after %d", res.StatusCode, hits.Load(), tt.wantCode, tt.wantHits)
+			}
+		})
+	}
+}
+
+func TestFetchRetriesNetworkErrors(t *testing.T) {
+	var calls int
+	useFetcher(t, fetcherFunc(func(ctx context.Context, url string) (*http.Response, error) {
+		calls++
+		if calls < fetchAttempts {
+			return nil, errors.New("connection reset by peer")
+		}
+		return htmlResponse("<p>ok</p>"), nil
+	}))
+
+	res, err := fetch(context.Background(), "https://example.test/")
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	res.Body.Close()
+	if calls != fetchAttempts {
+		t.Errorf("made %d attempts, want %d", calls, fetchAttempts)
+	}
+}
+
+func TestFetchStopsRetryingWhenCancelled(t *testing.T) {
+	useFetcher(t, fetcherFunc(func(ctx context.Context, url string) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := fetch(ctx, "https://example.test/"); !errors.Is(err, context.Canceled) {
+		t.Errorf("fetch returned %v, want context.Canceled", err)
+	}
+}
+
+func TestStrongsBatchIsNotLimitedPerItem(t *testing.T) {
+	useTestDB(t)
+	var fetches atomic.Int32
+	useFetcher(t, fetcherFunc(func(ctx context.Context, url string) (*http.Response, error) {
+		fetches.Add(1)
+		return nil, errors.New("offline")
+	}))
+	mux := newMux("", 0, nil)
+
+	// More uncached words than a single lookup's burst, as a chapter preload has.
+	words := make([]string, strongsRateBurst+5)
+	for i := range words {
+		words[i] = `{"word":"word` + strconv.Itoa(i) + `","reference":{"translation":"KJV","bookName":"John","chapter":3,"verse":16}}`
+	}
+	rec := serve(mux, http.MethodPost, "/api/strongs/batch", `[`+strings.Join(words, ",")+`]`, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var results []StrongsBatchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	for i, res := range results {
+		if res.Status == http.StatusTooManyRequests {
+			t.Errorf("item %d was rate limited: %+v", i, res)
+		}
+	}
+	if n := fetches.Load(); n < int32(len(words)) {
+		t.Errorf("%d fetches for %d uncached words", n, len(words))
+	}
+
+	// The batches themselves are limited.
+	for range strongsBatchRateBurst {
+		serve(mux, http.MethodPost, "/api/strongs/batch", `[]`, nil)
+	}
+	if rec := serve(mux, http.MethodPost, "/api/strongs/batch", `[]`, nil); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status %d after a burst of batches, want 429", rec.Code)
+	}
+}
+
+func TestStrongsBatchBodyLimit(t *testing.T) {
+	body := `[{"word":"` + strings.Repeat("x", maxStrongsBatchBodyBytes) + `"}]`
+	rec := httptest.NewRecorder()
+	strongsBatchHandler(rec, httptest.NewRequest(http.MethodPost, "/api/strongs/batch", strings.NewReader(body)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status %d, want 413: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestStrongsNumberLookup(t *testing.T) {
+	useTestDB(t)
+	var fetched []string
+	useFetcher(t, fetcherFunc(func(ctx context.Context, url string) (*http.Response, error) {
+		fetched = append(fetched, url)
+		return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(""))}, nil
+	}))
+	lexicon := &lexiconProvider{entries: map[string]lexiconEntry{"H157": {Lemma: "אָהַב", StrongsDef: "to have affection for"}}}
+	old := strongsProviders
+	strongsProviders = []StrongsProvider{blbProvider{}, lexicon}
+	t.Cleanup(func() { strongsProviders = old })
+	if err := cacheStrongsDefinition(context.Background(), StrongsDefinition{StrongsNumber: "G25", Definition: "to love"}, "loved", "KJV", "John", "3", "16"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		number  string
+		want    int
+		reply   string // substring of the response
+		fetched string // URL requested from Blue Letter Bible, if any
+	}{
+		{"g25", http.StatusOK, `"definition":"to love"`, ""},
+		{"H157", http.StatusOK, `"definition":"to have affection for"`, "https://www.blueletterbible.org/lexicon/h157/kjv/wlc/0-1/"},
+		{"G26", http.StatusBadGateway, "non-200 status: 404", "https://www.blueletterbible.org/lexicon/g26/kjv/tr/0-1/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			fetched = nil
+			rec := httptest.NewRecorder()
+			strongsNumberHandler(rec, httptest.NewRequest(http.MethodGet, "/api/strongs/"+tt.number, nil))
+			if rec.Code != tt.want || !strings.Contains(rec.Body.String(), tt.reply) {
+				t.Errorf("got %d %s, want %d containing %s", rec.Code, rec.Body, tt.want, tt.reply)
+			}
+			if got := strings.Join(fetched, " "); got != tt.fetched {
+				t.Errorf("fetched %q, want %q", got, tt.fetched)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// highlightChangesHandler returns the user's highlights changed at or after
+// since, oldest change first, for clients that keep a local copy in sync.
+// Highlights moved to the trash are included as tombstones with deletedAt
+// set; restoring one reports it again without. Timestamps have one-second
+// resolution, so the comparison is inclusive: a client passing the updatedAt
+// of the last change it saw gets that change again rather than missing
+// another made in the same second. Tombstones last as long as the trash, so
+// a client that has not synced for trashRetention should fetch everything.
+func highlightChangesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	sinceStr := r.URL.Query().Get("since")
+	if sinceStr == "" {
+		writeJSONError(w, http.StatusBadRequest, "Missing required query parameter: since")
+		return
+	}
+	since, err := time.Parse(time.RFC3339, sinceStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "since must be an RFC 3339 timestamp, e.g. 2024-01-01T00:00:00Z")
+		return
+	}
+
+	limit, offset, err := parsePagination(r, defaultHighlightsLimit, maxHighlightsLimit)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := `SELECT ` + highlightColumns + ` FROM highlights WHERE userId = ? AND updatedAt >= ?
+	          ORDER BY updatedAt, rowid LIMIT ? OFFSET ?`
+	rows, err := db.QueryContext(r.Context(), query, userIDFromContext(r.Context()), since.UTC().Format(time.RFC3339), limit, offset)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	highlights := []Highlight{}
+	for rows.Next() {
+		h, err := scanHighlight(rows)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		highlights = append(highlights, h)
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(highlights)
+}
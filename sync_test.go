@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestHighlightChangesSince(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	mux.HandleFunc("/api/highlights/changes", requireUser(highlightChangesHandler))
+	ann := signUp(t, mux, "ann") // user 1
+	signUp(t, mux, "bob")        // user 2
+
+	// Stored out of order, so sorting by rowid would give the wrong answer.
+	for _, h := range []Highlight{
+		{ID: "january", UserID: 1, UpdatedAt: "2024-01-01T00:00:00Z"},
+		{ID: "june", UserID: 1, UpdatedAt: "2024-06-01T00:00:00Z"},
+		{ID: "march", UserID: 1, UpdatedAt: "2024-03-01T00:00:00Z"},
+		{ID: "bobs", UserID: 2, UpdatedAt: "2024-03-01T00:00:00Z"},
+	} {
+		h.Type, h.VerseID, h.Translation, h.BookID, h.Chapter, h.CreatedAt = "highlight-only", "verse-1-1-1", "KJV", 1, 1, h.UpdatedAt
+		if _, err := insertHighlightStmt.Exec(insertHighlightArgs(h)...); err != nil {
+			t.Fatalf("storing %s: %v", h.ID, err)
+		}
+	}
+
+	changes := func(since string) []Highlight {
+		t.Helper()
+		rec := serve(mux, http.MethodGet, "/api/highlights/changes?since="+since, "", ann)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("since %s: status %d: %s", since, rec.Code, rec.Body)
+		}
+		var got []Highlight
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decoding body: %v", err)
+		}
+		return got
+	}
+	ids := func(hs []Highlight) []string {
+		var ids []string
+		for _, h := range hs {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+
+	if got, want := ids(changes("2024-02-01T00:00:00Z")), []string{"march", "june"}; !slices.Equal(got, want) {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+	// The boundary is inclusive, so a change made in the same second as the
+	// last one seen is not missed.
+	if got, want := ids(changes("2024-06-01T00:00:00Z")), []string{"june"}; !slices.Equal(got, want) {
+		t.Errorf("changes at the boundary = %v, want %v", got, want)
+	}
+
+	// Deleting january makes it the newest change, as a tombstone.
+	if rec := serve(mux, http.MethodDelete, "/api/highlights/delete/january", "", ann); rec.Code != http.StatusOK {
+		t.Fatalf("delete: status %d: %s", rec.Code, rec.Body)
+	}
+	got := changes("2024-02-01T00:00:00Z")
+	if want := []string{"march", "june", "january"}; !slices.Equal(ids(got), want) {
+		t.Fatalf("changes after delete = %v, want %v", ids(got), want)
+	}
+	if got[2].DeletedAt == "" || got[0].DeletedAt != "" {
+		t.Errorf("deletedAt = %q on the tombstone and %q on a live highlight", got[2].DeletedAt, got[0].DeletedAt)
+	}
+
+	if rec := serve(mux, http.MethodGet, "/api/highlights/changes?since=yesterday", "", ann); rec.Code != http.StatusBadRequest {
+		t.Errorf("unparseable since: status %d, want 400", rec.Code)
+	}
+}
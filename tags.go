@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// tagSeparator joins tag names in the group_concat column of
+// highlightColumns. It is the ASCII unit separator, which cannot appear in a
+// tag name that has been through cleanTags.
+const tagSeparator = "\x1f"
+
+// tagsColumn selects a highlight's tag names as one separated string. It is
+// correlated with the outer query, which must select FROM highlights.
+const tagsColumn = `(SELECT group_concat(t.name, char(31)) FROM highlight_tags ht JOIN tags t ON t.id = ht.tagId
+	          WHERE ht.highlightId = highlights.id)`
+
+// splitTags turns the tagsColumn value back into a sorted slice.
+func splitTags(joined sql.NullString) []string {
+	if !joined.Valid || joined.String == "" {
+		return []string{}
+	}
+	tags := strings.Split(joined.String, tagSeparator)
+	slices.Sort(tags)
+	return tags
+}
+
+// cleanTags trims tag names, strips control characters and drops empty names
+// and duplicates (ignoring case). The result is sorted like splitTags.
+func cleanTags(tags []string) []string {
+	cleaned := []string{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(strings.Map(func(r rune) rune {
+			if r < ' ' {
+				return -1
+			}
+			return r
+		}, tag))
+		if tag == "" || slices.ContainsFunc(cleaned, func(c string) bool { return strings.EqualFold(c, tag) }) {
+			continue
+		}
+		cleaned = append(cleaned, tag)
+	}
+	slices.Sort(cleaned)
+	return cleaned
+}
+
+// setHighlightTags replaces the tags of highlight h with h.Tags, creating any
+// tag names the user has not used before. It runs inside the caller's
+// transaction so a highlight and its tags are stored together.
+func setHighlightTags(ctx context.Context, tx *sql.Tx, h Highlight) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM highlight_tags WHERE highlightId = ?`, h.ID); err != nil {
+		return err
+	}
+
+	for _, tag := range cleanTags(h.Tags) {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO tags (userId, name) VALUES (?, ?)`, h.UserID, tag); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO highlight_tags (highlightId, tagId)
+		                   SELECT ?, id FROM tags WHERE userId = ? AND name = ?`, h.ID, h.UserID, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// highlightsByTagHandler returns the current user's highlights bearing tag,
+// in canonical verse order.
+func highlightsByTagHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	tag := strings.TrimSpace(r.URL.Query().Get("tag"))
+	if tag == "" {
+		writeJSONError(w, http.StatusBadRequest, "Missing required query parameter: tag")
+		return
+	}
+
+	userID := userIDFromContext(r.Context())
+	query := `SELECT ` + highlightColumns + ` FROM highlights
+	          WHERE userId = ? AND deletedAt IS NULL AND id IN (
+	              SELECT ht.highlightId FROM highlight_tags ht JOIN tags t ON t.id = ht.tagId
+	              WHERE t.userId = ? AND t.name = ?)
+	          ORDER BY translation, bookId, chapter, ` + verseNumberSQL + `, start`
+
+	rows, err := db.QueryContext(r.Context(), query, userID, userID, tag)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	highlights := []Highlight{}
+	for rows.Next() {
+		h, err := scanHighlight(rows)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		highlights = append(highlights, h)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(highlights)
+}
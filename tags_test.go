@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHighlightsByTagInVerseOrder(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	mux.HandleFunc("/api/highlights/by_tag", requireUser(highlightsByTagHandler))
+	ann := signUp(t, mux, "ann")
+
+	// Created out of order, and with verse 10 sorting before verse 9 as text.
+	for _, verse := range []string{"10", "9", "1"} {
+		body := strings.NewReplacer(
+			`"verseId":"verse-1-1-1"`, `"verseId":"verse-1-1-`+verse+`","tags":["creation"]`,
+		).Replace(testHighlight("h-" + verse))
+		if rec := serve(mux, http.MethodPost, "/api/highlights", body, ann); rec.Code != http.StatusCreated {
+			t.Fatalf("create verse %s: status %d: %s", verse, rec.Code, rec.Body)
+		}
+	}
+
+	rec := serve(mux, http.MethodGet, "/api/highlights/by_tag?tag=creation", "", ann)
+	var got []Highlight
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("status %d: decoding body: %v", rec.Code, err)
+	}
+	var ids []string
+	for _, h := range got {
+		ids = append(ids, h.ID)
+	}
+	if want := []string{"h-1", "h-9", "h-10"}; !slices.Equal(ids, want) {
+		t.Errorf("results = %v, want %v", ids, want)
+	}
+}
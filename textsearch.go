@@ -0,0 +1,243 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// textSearchEnabled reports whether the SQLite driver was built with FTS5
+// (go build -tags sqlite_fts5) and verses_fts is being kept up to date.
+// Without it /api/search/text answers 501.
+var textSearchEnabled bool
+
+// Result limits for Bible text search.
+const (
+	defaultTextSearchLimit = 20
+	maxTextSearchLimit     = 100
+)
+
+// The verses_fts index mirrors the plainText column of the verses table
+// through triggers, so that every chapter stored by fetchChapterVerses
+// becomes searchable. Indexing the text without markup keeps tag and
+// attribute names from matching and snippets from cutting through a tag.
+const verseIndexSQL = `
+	CREATE VIRTUAL TABLE IF NOT EXISTS verses_fts USING fts5(plainText, content='verses', content_rowid='rowid');
+	CREATE TRIGGER IF NOT EXISTS verses_fts_insert AFTER INSERT ON verses BEGIN
+		INSERT INTO verses_fts(rowid, plainText) VALUES (new.rowid, new.plainText);
+	END;
+	CREATE TRIGGER IF NOT EXISTS verses_fts_delete AFTER DELETE ON verses BEGIN
+		INSERT INTO verses_fts(verses_fts, rowid, plainText) VALUES ('delete', old.rowid, old.plainText);
+	END;
+	CREATE TRIGGER IF NOT EXISTS verses_fts_update AFTER UPDATE ON verses BEGIN
+		INSERT INTO verses_fts(verses_fts, rowid, plainText) VALUES ('delete', old.rowid, old.plainText);
+		INSERT INTO verses_fts(rowid, plainText) VALUES (new.rowid, new.plainText);
+	END;`
+
+// dropVerseIndexSQL removes verses_fts and its triggers.
+const dropVerseIndexSQL = `
+	DROP TRIGGER IF EXISTS verses_fts_insert;
+	DROP TRIGGER IF EXISTS verses_fts_delete;
+	DROP TRIGGER IF EXISTS verses_fts_update;
+	DROP TABLE IF EXISTS verses_fts;`
+
+// backfillPlainText fills in the plainText of verses stored before the column
+// existed.
+func backfillPlainText(conn *sql.DB) error {
+	rows, err := conn.Query(`SELECT rowid, text FROM verses WHERE plainText = '' AND text != ''`)
+	if err != nil {
+		return err
+	}
+	type verse struct {
+		rowid int64
+		text  string
+	}
+	var pending []verse
+	for rows.Next() {
+		var v verse
+		if err := rows.Scan(&v.rowid, &v.text); err != nil {
+			rows.Close()
+			return err
+		}
+		pending = append(pending, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(pending) == 0 {
+		return err
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+	for _, v := range pending {
+		if _, err := tx.Exec(`UPDATE verses SET plainText = ? WHERE rowid = ?`, verseText(v.text), v.rowid); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// setupTextSearch creates the verses_fts index if the driver supports FTS5,
+// rebuilding it from verses whenever its triggers were missing, and reports
+// whether text search is available. This lives outside the migrations
+// because it depends on how the binary was built: a build without FTS5
+// drops the triggers instead, since verse inserts would otherwise fail on
+// the unknown module, and the next FTS5 build catches the index up. An
+// index over the text with markup, from before plainText, is replaced.
+func setupTextSearch(conn *sql.DB) (bool, error) {
+	if err := backfillPlainText(conn); err != nil {
+		return false, err
+	}
+
+	var hadTriggers, outdated bool
+	if err := conn.QueryRow(`SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'trigger' AND name = 'verses_fts_insert'`).Scan(&hadTriggers); err != nil {
+		return false, err
+	}
+	if err := conn.QueryRow(`SELECT COUNT(*) > 0 FROM sqlite_master WHERE name = 'verses_fts' AND sql NOT LIKE '%plainText%'`).Scan(&outdated); err != nil {
+		return false, err
+	}
+
+	var available bool
+	if err := conn.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&available); err != nil {
+		return false, err
+	}
+	if !available {
+		_, err := conn.Exec(`DROP TRIGGER IF EXISTS verses_fts_insert;
+			DROP TRIGGER IF EXISTS verses_fts_delete;
+			DROP TRIGGER IF EXISTS verses_fts_update;`)
+		return false, err
+	}
+
+	if outdated {
+		if _, err := conn.Exec(dropVerseIndexSQL); err != nil {
+			return false, err
+		}
+		hadTriggers = false
+	}
+	if _, err := conn.Exec(verseIndexSQL); err != nil {
+		return false, err
+	}
+	if !hadTriggers {
+		if _, err := conn.Exec(`INSERT INTO verses_fts(verses_fts) VALUES ('rebuild')`); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// ftsQuery turns a user's search into an FTS5 query matching verses that
+// contain every word, where text in double quotes must appear as a phrase.
+// Each term is quoted so that FTS5 operators and punctuation are matched
+// literally rather than rejected as syntax errors. It returns "" if q has
+// no terms.
+func ftsQuery(q string) string {
+	var terms []string
+	for i, part := range strings.Split(q, `"`) {
+		// Odd parts sit between a pair of quotes; an unclosed quote runs to
+		// the end of q.
+		var phrases []string
+		if i%2 == 1 {
+			phrases = []string{part}
+		} else {
+			phrases = strings.Fields(part)
+		}
+		for _, p := range phrases {
+			if p = strings.Join(strings.Fields(p), " "); p != "" {
+				terms = append(terms, `"`+p+`"`)
+			}
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// TextSearchResult is a verse matching a Bible text search. Snippet is an
+// excerpt of the verse with each match wrapped in <mark> tags.
+type TextSearchResult struct {
+	VerseID     string `json:"verseId"`
+	Translation string `json:"translation"`
+	BookID      int    `json:"bookId"`
+	Chapter     int    `json:"chapter"`
+	Verse       int    `json:"verse"`
+	Reference   string `json:"reference"`
+	Snippet     string `json:"snippet"`
+}
+
+// textSearchHandler searches the text of stored verses, optionally in one
+// translation, returning the best matches first. Only chapters that have
+// been read, and so fetched into the verses table, are searchable.
+func textSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !textSearchEnabled {
+		writeJSONError(w, http.StatusNotImplemented, "Text search is not available in this build")
+		return
+	}
+
+	match := ftsQuery(r.URL.Query().Get("q"))
+	if match == "" {
+		writeJSONError(w, http.StatusBadRequest, "Missing required query parameter: q")
+		return
+	}
+
+	limit := defaultTextSearchLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		var err error
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxTextSearchLimit {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("limit must be an integer between 1 and %d", maxTextSearchLimit))
+			return
+		}
+	}
+
+	query := `SELECT v.translation, v.bookId, v.chapter, v.verse,
+	                 snippet(verses_fts, 0, '<mark>', '</mark>', '…', 16)
+	          FROM verses_fts JOIN verses v ON v.rowid = verses_fts.rowid
+	          WHERE verses_fts MATCH ?`
+	args := []any{match}
+	if translation := r.URL.Query().Get("translation"); translation != "" {
+		query += ` AND v.translation = ?`
+		args = append(args, translation)
+	}
+	query += ` ORDER BY rank LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.QueryContext(r.Context(), query, args...)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	results := []TextSearchResult{}
+	for rows.Next() {
+		var res TextSearchResult
+		if err := rows.Scan(&res.Translation, &res.BookID, &res.Chapter, &res.Verse, &res.Snippet); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		res.VerseID = verseID(res.BookID, res.Chapter, res.Verse)
+		res.Reference = fmt.Sprintf("%d:%d", res.Chapter, res.Verse)
+		if res.BookID >= 1 && res.BookID <= len(metadata.Books) {
+			res.Reference = metadata.Books[res.BookID-1].Name + " " + res.Reference
+		}
+		results = append(results, res)
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
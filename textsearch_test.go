@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTextSearchIgnoresMarkup(t *testing.T) {
+	useTestDB(t)
+	// Stored as by a version without plainText, for setupTextSearch to
+	// backfill.
+	if _, err := db.Exec(`INSERT INTO verses (translation, bookId, chapter, verse, text) VALUES ('KJV', 1, 1, 1, ?)`,
+		`In the <span class="highlight">beginning</span> God created the heaven<sup>1</sup> and the earth.`); err != nil {
+		t.Fatal(err)
+	}
+	enabled, err := setupTextSearch(db)
+	if err != nil {
+		t.Fatalf("setupTextSearch: %v", err)
+	}
+
+	var plain string
+	if err := db.QueryRow(`SELECT plainText FROM verses WHERE verse = 1`).Scan(&plain); err != nil {
+		t.Fatal(err)
+	}
+	if strings.ContainsAny(plain, "<>") || !strings.Contains(plain, "beginning God") {
+		t.Errorf("plainText = %q, want the text without markup", plain)
+	}
+
+	if !enabled {
+		t.Skip("SQLite built without FTS5")
+	}
+	old := textSearchEnabled
+	textSearchEnabled = true
+	t.Cleanup(func() { textSearchEnabled = old })
+
+	search := func(q string) []TextSearchResult {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		textSearchHandler(rec, httptest.NewRequest(http.MethodGet, "/api/search/text?q="+q, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("search %q: status %d: %s", q, rec.Code, rec.Body)
+		}
+		var results []TextSearchResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+			t.Fatal(err)
+		}
+		return results
+	}
+	if results := search("highlight"); len(results) != 0 {
+		t.Errorf("search for an attribute value matched %v", results)
+	}
+	results := search("beginning")
+	if len(results) != 1 {
+		t.Fatalf("search for a word got %d results, want 1", len(results))
+	}
+	snippet := strings.NewReplacer("<mark>", "", "</mark>", "").Replace(results[0].Snippet)
+	if strings.ContainsAny(snippet, "<>") {
+		t.Errorf("snippet %q contains markup", results[0].Snippet)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// trashRetention is how long a deleted highlight stays restorable.
+const trashRetention = 30 * 24 * time.Hour
+
+// trashPurgeInterval is how often purgeTrashPeriodically runs.
+const trashPurgeInterval = time.Hour
+
+// restoreSuffix ends the path of a highlight's restore endpoint.
+const restoreSuffix = "/restore"
+
+// trashHandler lists the current user's deleted highlights that can still be
+// restored, most recently deleted first.
+func trashHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	limit, offset, err := parsePagination(r, defaultHighlightsLimit, maxHighlightsLimit)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := `SELECT ` + highlightColumns + ` FROM highlights WHERE userId = ? AND deletedAt IS NOT NULL
+	          ORDER BY deletedAt DESC, rowid DESC LIMIT ? OFFSET ?`
+	rows, err := db.QueryContext(r.Context(), query, userIDFromContext(r.Context()), limit, offset)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer rows.Close()
+
+	highlights := []Highlight{}
+	for rows.Next() {
+		h, err := scanHighlight(rows)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+			slog.Error("Database error", "err", err)
+			return
+		}
+		highlights = append(highlights, h)
+	}
+	if err := rows.Err(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Database query failed")
+		slog.Error("Database error", "err", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(highlights)
+}
+
+// restoreHighlightHandler takes a highlight back out of the trash and returns
+// it. Restoring counts as an update, so only updatedAt differs from the
+// highlight as it was before it was deleted.
+func restoreHighlightHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	id, err := highlightIDFromPathSegment(r, "/api/highlights/", restoreSuffix)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID := userIDFromContext(r.Context())
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to begin transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	result, err := tx.ExecContext(r.Context(), `UPDATE highlights SET deletedAt = NULL, updatedAt = ? WHERE id = ? AND userId = ? AND deletedAt IS NOT NULL`, timestamp(), id, userID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to execute statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		writeJSONError(w, http.StatusNotFound, "Highlight not found in trash")
+		return
+	}
+
+	restored, err := scanHighlight(tx.StmtContext(r.Context(), selectHighlightStmt).QueryRowContext(r.Context(), id, userID))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to scan row")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	restored.UserID = userID
+
+	if err := tx.Commit(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to commit transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	hub.publishHighlight("created", restored)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(restored)
+}
+
+// purgeTrash permanently deletes highlights that have been in the trash for
+// longer than trashRetention, along with their note history, returning how
+// many were removed. Tag links go with them through their foreign key.
+func purgeTrash(ctx context.Context, conn *sql.DB, now time.Time) (int64, error) {
+	cutoff := now.Add(-trashRetention).UTC().Format(time.RFC3339)
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM highlight_note_history WHERE highlightId IN
+	                              (SELECT id FROM highlights WHERE deletedAt IS NOT NULL AND deletedAt < ?)`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM highlights WHERE deletedAt IS NOT NULL AND deletedAt < ?`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// purgeTrashPeriodically runs purgeTrash on db at startup and then every
+// trashPurgeInterval for the life of the process.
+func purgeTrashPeriodically() {
+	for {
+		n, err := purgeTrash(context.Background(), db, time.Now())
+		if err != nil {
+			slog.Error("Trash purge failed", "err", err)
+		} else if n > 0 {
+			slog.Info("Purged highlights from trash", "count", n)
+		}
+		time.Sleep(trashPurgeInterval)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTrashRestoreAndPurge(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	mux.HandleFunc("/api/highlights/trash", requireUser(trashHandler))
+	ann := signUp(t, mux, "ann")
+
+	for _, id := range []string{"kept", "purged"} {
+		if rec := serve(mux, http.MethodPost, "/api/highlights", testHighlight(id), ann); rec.Code != http.StatusCreated {
+			t.Fatalf("create %s: status %d: %s", id, rec.Code, rec.Body)
+		}
+	}
+	// Replacing a note leaves the first in the history, which goes too.
+	for _, note := range []string{"first thoughts", "second thoughts"} {
+		if rec := serve(mux, http.MethodPut, "/api/highlights/purged", `{"note":"`+note+`"}`, ann); rec.Code != http.StatusOK {
+			t.Fatalf("update: status %d: %s", rec.Code, rec.Body)
+		}
+	}
+	for _, id := range []string{"kept", "purged"} {
+		if rec := serve(mux, http.MethodDelete, "/api/highlights/delete/"+id, "", ann); rec.Code != http.StatusOK {
+			t.Fatalf("delete %s: status %d: %s", id, rec.Code, rec.Body)
+		}
+	}
+
+	trash := func() []string {
+		t.Helper()
+		rec := serve(mux, http.MethodGet, "/api/highlights/trash", "", ann)
+		var got []Highlight
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("trash: status %d: decoding body: %v", rec.Code, err)
+		}
+		var ids []string
+		for _, h := range got {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+	if got, want := trash(), []string{"purged", "kept"}; !slices.Equal(got, want) {
+		t.Errorf("trash = %v, want %v", got, want)
+	}
+
+	if rec := serve(mux, http.MethodPost, "/api/highlights/kept/restore", "", ann); rec.Code != http.StatusOK {
+		t.Fatalf("restore: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(mux, http.MethodGet, "/api/highlights/kept", "", ann); rec.Code != http.StatusOK {
+		t.Errorf("restored highlight: status %d", rec.Code)
+	}
+	if rec := serve(mux, http.MethodPost, "/api/highlights/kept/restore", "", ann); rec.Code != http.StatusNotFound {
+		t.Errorf("restoring twice: status %d, want 404", rec.Code)
+	}
+	if got, want := trash(), []string{"purged"}; !slices.Equal(got, want) {
+		t.Errorf("trash after restore = %v, want %v", got, want)
+	}
+
+	if n, err := purgeTrash(context.Background(), db, time.Now()); err != nil || n != 0 {
+		t.Errorf("purge within retention = %d, %v; want nothing purged", n, err)
+	}
+	if n, err := purgeTrash(context.Background(), db, time.Now().Add(trashRetention+time.Hour)); err != nil || n != 1 {
+		t.Errorf("purge after retention = %d, %v; want 1", n, err)
+	}
+	if got := trash(); len(got) != 0 {
+		t.Errorf("trash after purge = %v, want it empty", got)
+	}
+	var history int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM highlight_note_history WHERE highlightId = 'purged'`).Scan(&history); err != nil || history != 0 {
+		t.Errorf("note history of the purged highlight: %d rows, err %v", history, err)
+	}
+	if rec := serve(mux, http.MethodGet, "/api/highlights/kept", "", ann); rec.Code != http.StatusOK {
+		t.Errorf("purge removed a restored highlight: status %d", rec.Code)
+	}
+}
+
+func TestPostingTrashedHighlightRestoresIt(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	mux.HandleFunc("/api/highlights/trash", requireUser(trashHandler))
+	ann := signUp(t, mux, "ann")
+	bob := signUp(t, mux, "bob")
+
+	rec := serve(mux, http.MethodPost, "/api/highlights", testHighlight("h-1"), ann)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", rec.Code, rec.Body)
+	}
+	var created Highlight
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if rec := serve(mux, http.MethodPost, "/api/highlights", testHighlight("h-1"), ann); rec.Code != http.StatusConflict {
+		t.Errorf("posting a live ID: status %d, want 409", rec.Code)
+	}
+
+	rec = serve(mux, http.MethodDelete, "/api/highlights/delete/h-1", "", ann)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("delete: status %d: %s", rec.Code, rec.Body)
+	}
+	deleted := rec.Body.String()
+	if rec := serve(mux, http.MethodPost, "/api/highlights", deleted, bob); rec.Code != http.StatusConflict {
+		t.Errorf("posting another user's trashed ID: status %d, want 409", rec.Code)
+	}
+
+	// Undo posts back what DELETE returned.
+	rec = serve(mux, http.MethodPost, "/api/highlights", deleted, ann)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("undo: status %d: %s", rec.Code, rec.Body)
+	}
+	var restored Highlight
+	if err := json.NewDecoder(rec.Body).Decode(&restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.CreatedAt != created.CreatedAt {
+		t.Errorf("createdAt = %q, want the original %q", restored.CreatedAt, created.CreatedAt)
+	}
+	if rec := serve(mux, http.MethodGet, "/api/highlights/h-1", "", ann); rec.Code != http.StatusOK {
+		t.Errorf("restored highlight: status %d", rec.Code)
+	}
+	if rec := serve(mux, http.MethodGet, "/api/highlights/trash", "", ann); rec.Body.String() != "[]\n" {
+		t.Errorf("trash after undo = %s, want it empty", rec.Body)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// bollsURL is the source of Bible text, shared with the frontend.
+const bollsURL = "https://bolls.life"
+
+// Verse is a single verse of a chapter as returned by /api/verses.
+type Verse struct {
+	VerseID     string `json:"verseId"`
+	VerseNumber int    `json:"verseNumber"`
+	Text        string `json:"text"`
+}
+
+// verseTextColumn selects the stored text of a highlight's verse, the first
+// one for a span, or "" if that chapter has not been fetched. Like tagsColumn
+// it is correlated with an outer query selecting FROM highlights; the columns
+// are qualified because verses has its own translation, bookId and chapter.
+const verseTextColumn = `COALESCE((SELECT v.text FROM verses v
+	          WHERE v.translation = highlights.translation AND v.bookId = highlights.bookId AND v.chapter = highlights.chapter
+	              AND v.verse = CAST(replace(highlights.verseId, 'verse-' || highlights.bookId || '-' || highlights.chapter || '-', '') AS INTEGER)), '')`
+
+// verseID builds the identifier the frontend gives each verse element, which
+// is also what highlights store in their verseId column.
+func verseID(bookID, chapter, verse int) string {
+	return fmt.Sprintf("verse-%d-%d-%d", bookID, chapter, verse)
+}
+
+// versesHandler returns the verses of a chapter in order. Chapters are read
+// from the verses table, and fetched from bolls.life on first request.
+func versesHandler(w http.ResponseWriter, r *http.Request) {
+	translation, bookID, chapter, err := parseChapterQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	verses, err := loadChapterVerses(r.Context(), translation, bookID, chapter)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to load verses")
+		slog.Error("Verse load failed", "err", err)
+		return
+	}
+	if len(verses) == 0 {
+		writeJSONError(w, http.StatusNotFound, "Chapter not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verses)
+}
+
+// loadChapterVerses returns the stored verses for a chapter, fetching and
+// storing them from bolls.life if the chapter has not been seen before. An
+// empty result means the chapter does not exist in that translation. Chapters
+// missing from the bundled metadata are never fetched, so made-up references
+// cannot send requests upstream.
+func loadChapterVerses(ctx context.Context, translation string, bookID, chapter int) ([]Verse, error) {
+	if metadataVerseCount(bookID, chapter) == 0 {
+		return []Verse{}, nil
+	}
+	verses, err := queryChapterVerses(ctx, translation, bookID, chapter)
+	if err != nil || len(verses) > 0 {
+		return verses, err
+	}
+
+	if err := fetchChapterVerses(ctx, translation, bookID, chapter); err != nil {
+		return nil, err
+	}
+	return queryChapterVerses(ctx, translation, bookID, chapter)
+}
+
+func queryChapterVerses(ctx context.Context, translation string, bookID, chapter int) ([]Verse, error) {
+	query := `SELECT verse, text FROM verses
+	          WHERE translation = ? AND bookId = ? AND chapter = ?
+	          ORDER BY verse`
+
+	rows, err := db.QueryContext(ctx, query, translation, bookID, chapter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	verses := []Verse{}
+	for rows.Next() {
+		var v Verse
+		if err := rows.Scan(&v.VerseNumber, &v.Text); err != nil {
+			return nil, err
+		}
+		v.VerseID = verseID(bookID, chapter, v.VerseNumber)
+		verses = append(verses, v)
+	}
+	return verses, rows.Err()
+}
+
+// fetchChapterVerses downloads a chapter from bolls.life into the verses
+// table. A 404 from bolls.life is not an error; it just stores nothing.
+func fetchChapterVerses(ctx context.Context, translation string, bookID, chapter int) error {
+	chapterURL := fmt.Sprintf("%s/get-chapter/%s/%d/%d/", bollsURL, url.PathEscape(translation), bookID, chapter)
+
+	res, err := fetch(ctx, chapterURL)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if res.StatusCode != 200 {
+		return fmt.Errorf("bolls.life returned status %d for %s", res.StatusCode, chapterURL)
+	}
+
+	var fetched []struct {
+		Verse int    `json:"verse"`
+		Text  string `json:"text"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&fetched); err != nil {
+		return fmt.Errorf("decoding %s: %w", chapterURL, err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT OR REPLACE INTO verses (translation, bookId, chapter, verse, text, plainText) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, v := range fetched {
+		if _, err := stmt.ExecContext(ctx, translation, bookID, chapter, v.Verse, v.Text, verseText(v.Text)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// fetcherFunc adapts a function to the Fetcher interface.
+type fetcherFunc func(ctx context.Context, url string) (*http.Response, error)
+
+func (f fetcherFunc) Get(ctx context.Context, url string) (*http.Response, error) { return f(ctx, url) }
+
+// useFetcher swaps the package-level fetcher for the rest of the test.
+func useFetcher(t *testing.T, f Fetcher) {
+	t.Helper()
+	old := fetcher
+	fetcher = f
+	t.Cleanup(func() { fetcher = old })
+}
+
+// jsonResponse is a canned 200 response with a JSON body.
+func jsonResponse(body string) *http.Response {
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"application/json"}}, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+// htmlResponse is a canned 200 response with an HTML body.
+func htmlResponse(body string) *http.Response {
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"text/html"}}, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestVersesHandler(t *testing.T) {
+	useTestDB(t)
+	var fetches atomic.Int32
+	useFetcher(t, fetcherFunc(func(ctx context.Context, url string) (*http.Response, error) {
+		fetches.Add(1)
+		return jsonResponse(`[{"verse":1,"text":"In the beginning"},{"verse":2,"text":"And the earth"}]`), nil
+	}))
+
+	tests := []struct {
+		name        string
+		query       string
+		want        int
+		wantFetches int32
+	}{
+		{"first read fetches", "translation=KJV&bookId=1&chapter=1", http.StatusOK, 1},
+		{"second read is stored", "translation=KJV&bookId=1&chapter=1", http.StatusOK, 1},
+		{"chapter past the end of the book", "translation=KJV&bookId=1&chapter=51", http.StatusNotFound, 1},
+		{"book past the end of the Bible", "translation=KJV&bookId=67&chapter=1", http.StatusNotFound, 1},
+		{"missing chapter", "translation=KJV&bookId=1", http.StatusBadRequest, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(http.HandlerFunc(versesHandler), http.MethodGet, "/api/verses?"+tt.query, "", nil)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if got := fetches.Load(); got != tt.wantFetches {
+				t.Errorf("%d fetches so far, want %d", got, tt.wantFetches)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// usfmBooks holds the USFM code YouVersion uses for each book, indexed by
+// bookId-1.
+var usfmBooks = [66]string{
+	"GEN", "EXO", "LEV", "NUM", "DEU", "JOS", "JDG", "RUT", "1SA", "2SA",
+	"1KI", "2KI", "1CH", "2CH", "EZR", "NEH", "EST", "JOB", "PSA", "PRO",
+	"ECC", "SNG", "ISA", "JER", "LAM", "EZK", "DAN", "HOS", "JOL", "AMO",
+	"OBA", "JON", "MIC", "NAM", "HAB", "ZEP", "HAG", "ZEC", "MAL",
+	"MAT", "MRK", "LUK", "JHN", "ACT", "ROM", "1CO", "2CO", "GAL", "EPH",
+	"PHP", "COL", "1TH", "2TH", "1TI", "2TI", "TIT", "PHM", "HEB", "JAS",
+	"1PE", "2PE", "1JN", "2JN", "3JN", "JUD", "REV",
+}
+
+// usfmBookIDs maps a USFM book code back to its bookId.
+var usfmBookIDs = func() map[string]int {
+	ids := make(map[string]int, len(usfmBooks))
+	for i, code := range usfmBooks {
+		ids[code] = i + 1
+	}
+	return ids
+}()
+
+// youVersionTranslations maps YouVersion's numeric version IDs to the codes
+// of the translations this app offers.
+var youVersionTranslations = map[int]string{
+	1:   "KJV",
+	59:  "ESV",
+	100: "NASB",
+	111: "NIV",
+	206: "WEB",
+	821: "YLT",
+}
+
+// YouVersionExport is the part of a YouVersion data export that is
+// imported: highlights and notes, each attached to one or more verses.
+type YouVersionExport struct {
+	Highlights []YouVersionMoment `json:"highlights"`
+	Notes      []YouVersionMoment `json:"notes"`
+}
+
+// YouVersionMoment is a highlight or note in a YouVersion export. USFM lists
+// the verses it covers as references like "JHN.3.16", and Color is a hex
+// value without the leading #.
+type YouVersionMoment struct {
+	USFM      []string `json:"usfm"`
+	VersionID int      `json:"version_id"`
+	Color     string   `json:"color"`
+	Content   string   `json:"content"`
+	CreatedDT string   `json:"created_dt"`
+}
+
+// parseUSFMRef splits a single-verse USFM reference such as "JHN.3.16",
+// rejecting chapters the book does not have.
+func parseUSFMRef(ref string) (bookID, chapter, verse int, ok bool) {
+	parts := strings.Split(strings.TrimSpace(ref), ".")
+	if len(parts) != 3 {
+		return 0, 0, 0, false
+	}
+	bookID, ok = usfmBookIDs[strings.ToUpper(parts[0])]
+	if !ok {
+		return 0, 0, 0, false
+	}
+	var err1, err2 error
+	chapter, err1 = strconv.Atoi(parts[1])
+	verse, err2 = strconv.Atoi(parts[2])
+	ok = err1 == nil && err2 == nil && chapter >= 1 && chapter <= metadata.Books[bookID-1].Chapters && verse >= 1
+	return bookID, chapter, verse, ok
+}
+
+// youVersionHighlights maps a moment onto highlights of type typ in
+// translation, owned by userID, one for each run of consecutive verses it
+// covers, as whole verse highlights. IDs are derived from the user and the
+// moment, so importing the same export twice updates the highlights rather
+// than duplicating them, and two accounts importing the same export never
+// collide. It returns a reason, worded for the client, if the moment cannot
+// be mapped.
+func youVersionHighlights(m YouVersionMoment, typ, translation string, userID int64) ([]Highlight, string) {
+	if len(m.USFM) == 0 {
+		return nil, "no verses given in usfm"
+	}
+	type ref struct{ book, chapter, verse int }
+	var refs []ref
+	for _, s := range m.USFM {
+		b, c, v, ok := parseUSFMRef(s)
+		if !ok {
+			return nil, fmt.Sprintf("unrecognized verse reference %q", s)
+		}
+		refs = append(refs, ref{b, c, v})
+	}
+	slices.SortFunc(refs, func(a, b ref) int {
+		if a.book != b.book {
+			return a.book - b.book
+		}
+		if a.chapter != b.chapter {
+			return a.chapter - b.chapter
+		}
+		return a.verse - b.verse
+	})
+	refs = slices.Compact(refs)
+
+	color := ""
+	if m.Color != "" {
+		color = "#" + strings.TrimPrefix(m.Color, "#")
+	}
+	createdAt := ""
+	if t, err := time.Parse(time.RFC3339, m.CreatedDT); err == nil {
+		createdAt = t.UTC().Format(time.RFC3339)
+	}
+
+	var highlights []Highlight
+	for i := 0; i < len(refs); {
+		first := refs[i]
+		j := i + 1
+		for j < len(refs) && refs[j].book == first.book && refs[j].chapter == first.chapter && refs[j].verse == refs[j-1].verse+1 {
+			j++
+		}
+		last := refs[j-1]
+
+		h := Highlight{
+			UserID:      userID,
+			Type:        typ,
+			VerseID:     verseID(first.book, first.chapter, first.verse),
+			Translation: translation,
+			BookID:      first.book,
+			Chapter:     first.chapter,
+			Color:       color,
+			CreatedAt:   createdAt,
+		}
+		if last != first {
+			h.EndVerseID = verseID(last.book, last.chapter, last.verse)
+		}
+		if typ == "note" {
+			h.Note = strings.TrimSpace(m.Content)
+		}
+		sum := sha256.Sum256([]byte(strings.Join([]string{strconv.FormatInt(userID, 10), typ, translation, h.VerseID, h.EndVerseID, m.CreatedDT}, "|")))
+		h.ID = "yv-" + hex.EncodeToString(sum[:8])
+		highlights = append(highlights, h)
+		i = j
+	}
+	return highlights, ""
+}
+
+// importYouVersionHandler imports the highlights and notes of a YouVersion
+// data export into the current user's account, in one transaction. Each
+// moment is stored in the translation its version_id names, or in the
+// translation query parameter when that is given; moments in a version this
+// app does not offer, or with references or colors that cannot be mapped,
+// are skipped and reported with their list and index.
+func importYouVersionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var export YouVersionExport
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+	err := json.NewDecoder(r.Body).Decode(&export)
+	if isBodyTooLarge(err) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, bodyTooLargeMessage(maxImportBodyBytes))
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request body: expected a YouVersion export with highlights and notes")
+		return
+	}
+	override := r.URL.Query().Get("translation")
+
+	tx, err := db.BeginTx(r.Context(), nil)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to begin transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer tx.Rollback() // No-op once the transaction has been committed
+
+	stmt, err := tx.PrepareContext(r.Context(), upsertHighlightSQL)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to prepare statement")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	defer stmt.Close()
+
+	userID := userIDFromContext(r.Context())
+	result := ImportResult{Skipped: []ImportSkip{}}
+	var inserted, updated []Highlight
+	lists := []struct {
+		kind    string
+		typ     string
+		moments []YouVersionMoment
+	}{
+		{"highlights", "highlight-only", export.Highlights},
+		{"notes", "note", export.Notes},
+	}
+	for _, list := range lists {
+		for i, m := range list.moments {
+			translation := override
+			if translation == "" {
+				var ok bool
+				if translation, ok = youVersionTranslations[m.VersionID]; !ok {
+					result.Skipped = append(result.Skipped, ImportSkip{Kind: list.kind, Index: i, Reason: fmt.Sprintf("YouVersion version %d is not available here; import it with translation set", m.VersionID)})
+					continue
+				}
+			}
+
+			highlights, reason := youVersionHighlights(m, list.typ, translation, userID)
+			for j := range highlights {
+				if reason != "" {
+					break
+				}
+				if err := highlights[j].validate(); err != nil {
+					reason = err.Error()
+				}
+			}
+			if reason != "" {
+				result.Skipped = append(result.Skipped, ImportSkip{Kind: list.kind, Index: i, Reason: reason})
+				continue
+			}
+
+			for _, h := range highlights {
+				stampHighlight(&h)
+				exists, skip, err := importHighlight(r.Context(), tx, stmt, &h)
+				if err != nil {
+					writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to import %s at index %d", list.kind, i))
+					slog.Error("Database error", "err", err)
+					return
+				}
+				if skip != "" {
+					result.Skipped = append(result.Skipped, ImportSkip{Kind: list.kind, Index: i, Reason: skip})
+					continue
+				}
+				if exists {
+					updated = append(updated, h)
+				} else {
+					inserted = append(inserted, h)
+				}
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to commit transaction")
+		slog.Error("Database error", "err", err)
+		return
+	}
+	for _, h := range inserted {
+		hub.publishHighlight("created", h)
+	}
+	for _, h := range updated {
+		hub.publishHighlight("updated", h)
+	}
+	result.Inserted, result.Updated = len(inserted), len(updated)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+)
+
+func TestYouVersionImportIsScopedToTheUser(t *testing.T) {
+	useTestDB(t)
+	mux := testMux()
+	ann := signUp(t, mux, "ann")
+	bob := signUp(t, mux, "bob")
+
+	export := `{"highlights":[{"usfm":["JHN.3.16","JHN.3.17"],"version_id":1,"color":"ffff00","created_dt":"2024-01-02T03:04:05Z"}],
+		"notes":[{"usfm":["GEN.1.1"],"version_id":1,"content":"In the beginning","created_dt":"2024-01-02T03:04:05Z"}]}`
+	// ann is user 1; this watches the chapter of the imported highlight.
+	watcher := hub.register(chapterKey{1, "KJV", 43, 3})
+	defer hub.unregister(watcher)
+
+	tests := []struct {
+		name   string
+		cookie *http.Cookie
+		want   ImportResult
+		event  string // sent to ann's John 3 watcher, if any
+	}{
+		{"ann imports", ann, ImportResult{Inserted: 2}, "created"},
+		{"bob imports the same export", bob, ImportResult{Inserted: 2}, ""},
+		{"ann imports again", ann, ImportResult{Updated: 2}, "updated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(mux, http.MethodPost, "/api/import/youversion", export, tt.cookie)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+			var got ImportResult
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("decoding body: %v", err)
+			}
+			if got.Inserted != tt.want.Inserted || got.Updated != tt.want.Updated || len(got.Skipped) != 0 {
+				t.Errorf("result = %+v, want %d inserted and %d updated", got, tt.want.Inserted, tt.want.Updated)
+			}
+			select {
+			case ev := <-watcher.send:
+				if ev.Type != tt.event {
+					t.Errorf("event = %s, want %q", ev.Type, tt.event)
+				}
+			default:
+				if tt.event != "" {
+					t.Errorf("no %s event was published", tt.event)
+				}
+			}
+		})
+	}
+
+	for name, cookie := range map[string]*http.Cookie{"ann": ann, "bob": bob} {
+		rec := serve(mux, http.MethodGet, "/api/highlights?translation=KJV&bookId=43&chapter=3", "", cookie)
+		var got []Highlight
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decoding body: %v", err)
+		}
+		if len(got) != 1 || got[0].EndVerseID != "verse-43-3-17" {
+			t.Errorf("John 3 for %s = %+v, want the one imported span", name, got)
+		}
+	}
+}
+
+func TestYouVersionHighlightsCoverWholeVerses(t *testing.T) {
+	m := YouVersionMoment{USFM: []string{"JHN.3.18", "JHN.3.16", "JHN.3.17", "JHN.3.20"}, VersionID: 1, Color: "ffff00"}
+	highlights, reason := youVersionHighlights(m, "highlight-only", "KJV", 1)
+	if reason != "" || len(highlights) != 2 {
+		t.Fatalf("got %+v, %q; want a span and a single verse", highlights, reason)
+	}
+
+	var verses []Verse
+	for v := 15; v <= 21; v++ {
+		verses = append(verses, Verse{VerseID: verseID(43, 3, v)})
+	}
+	got := highlightsByVerse(highlights, verses)
+	for v := 15; v <= 21; v++ {
+		pieces := got[verseID(43, 3, v)]
+		covered := v >= 16 && v <= 18 || v == 20
+		switch {
+		case !covered && len(pieces) != 0:
+			t.Errorf("John 3:%d has %+v, want nothing", v, pieces)
+		case covered && (len(pieces) != 1 || pieces[0].Start != 0 || pieces[0].End != math.MaxInt):
+			t.Errorf("John 3:%d has %+v, want the whole verse", v, pieces)
+		}
+	}
+}